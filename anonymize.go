@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PathAnonymizer rewrites local filesystem paths to neutral tokens so a
+// session can be shared without revealing directory layout or usernames.
+type PathAnonymizer struct {
+	replacements []pathReplacement
+	otherHomes   *regexp.Regexp
+}
+
+type pathReplacement struct {
+	from string
+	to   string
+	re   *regexp.Regexp
+}
+
+// homeDirRe matches home directories of any user, keeping the parent in
+// group 1 and the username in group 2.
+var homeDirRe = regexp.MustCompile(`(/home/|/Users/|[A-Za-z]:\\Users\\)([^/\\\s"'<>]+)`)
+
+// NewPathAnonymizer builds an anonymizer that maps project to $PROJECT, home
+// to ~ and each prefix to its own token. Prefixes are given as "path" or
+// "path=TOKEN"; without a token they become $PREFIX1, $PREFIX2, ...
+func NewPathAnonymizer(home, project string, prefixes []string) *PathAnonymizer {
+	a := &PathAnonymizer{otherHomes: homeDirRe}
+	for i, p := range prefixes {
+		path, token, ok := strings.Cut(p, "=")
+		if !ok || token == "" {
+			token = fmt.Sprintf("$PREFIX%d", i+1)
+		}
+		a.add(path, token)
+	}
+	a.add(project, "$PROJECT")
+	a.add(home, "~")
+	// Claude Code names project directories after the path with separators
+	// replaced by dashes, e.g. -home-alice-work-app.
+	if project != "" {
+		a.addEncoded(encodeProjectPath(project), "-project")
+	}
+	if home != "" {
+		a.addEncoded(encodeProjectPath(home), "-home-user")
+	}
+
+	sort.SliceStable(a.replacements, func(i, j int) bool {
+		return len(a.replacements[i].from) > len(a.replacements[j].from)
+	})
+	return a
+}
+
+func (a *PathAnonymizer) add(path, token string) {
+	a.addWithBoundary(strings.TrimRight(path, `/\`), token, `[^A-Za-z0-9_.\-]`)
+}
+
+// addEncoded adds a dash-encoded path, which is followed by more dashes
+// rather than a separator.
+func (a *PathAnonymizer) addEncoded(path, token string) {
+	a.addWithBoundary(path, token, `[^A-Za-z0-9_.]`)
+}
+
+func (a *PathAnonymizer) addWithBoundary(path, token, boundary string) {
+	if path == "" || path == "-" {
+		return
+	}
+	for _, r := range a.replacements {
+		if r.from == path {
+			return
+		}
+	}
+	a.replacements = append(a.replacements, pathReplacement{
+		from: path,
+		to:   token,
+		re:   regexp.MustCompile(regexp.QuoteMeta(path) + `(` + boundary + `|$)`),
+	})
+}
+
+var projectPathRe = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// encodeProjectPath mirrors how Claude Code names project directories: every
+// character other than an ASCII letter or digit becomes a dash.
+func encodeProjectPath(path string) string {
+	return projectPathRe.ReplaceAllString(filepath.Clean(path), "-")
+}
+
+func (a *PathAnonymizer) AnonymizeString(s string) string {
+	for _, r := range a.replacements {
+		if !strings.Contains(s, r.from) {
+			continue
+		}
+		s = r.re.ReplaceAllStringFunc(s, func(m string) string {
+			return r.to + m[len(r.from):]
+		})
+	}
+	return a.otherHomes.ReplaceAllString(s, "${1}user")
+}
+
+// AnonymizeMessages rewrites paths in every block of msgs in place.
+func (a *PathAnonymizer) AnonymizeMessages(msgs []Message) {
+	walkBlocks(msgs, func(b *ContentBlock) {
+		b.Text = a.AnonymizeString(b.Text)
+		b.ToolInput = mapJSONStrings(b.ToolInput, a.AnonymizeString)
+	})
+}
+
+// AnonymizeMeta rewrites paths in meta and hides the project name.
+func (a *PathAnonymizer) AnonymizeMeta(meta *SessionMeta) {
+	if meta.Project != "" {
+		meta.Project = "project"
+	}
+	meta.FirstPrompt = a.AnonymizeString(meta.FirstPrompt)
+	meta.Cwd = a.AnonymizeString(meta.Cwd)
+	meta.GitBranch = a.AnonymizeString(meta.GitBranch)
+}
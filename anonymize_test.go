@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymizeString_ProjectAndHome(t *testing.T) {
+	a := NewPathAnonymizer("/home/alice", "/home/alice/work/secret-client", nil)
+
+	assert.Equal(t, "Edited $PROJECT/src/main.go", a.AnonymizeString("Edited /home/alice/work/secret-client/src/main.go"))
+	assert.Equal(t, "See ~/.bashrc and ~/work/other", a.AnonymizeString("See /home/alice/.bashrc and /home/alice/work/other"))
+	assert.Equal(t, "cd $PROJECT", a.AnonymizeString("cd /home/alice/work/secret-client"))
+}
+
+func TestAnonymizeString_RespectsPathBoundaries(t *testing.T) {
+	a := NewPathAnonymizer("/home/alice", "/home/alice/work/app", nil)
+
+	assert.Equal(t, "~/work/app-v2/x", a.AnonymizeString("/home/alice/work/app-v2/x"))
+	assert.Equal(t, "/home/user/y", a.AnonymizeString("/home/alicex/y"))
+}
+
+func TestAnonymizeString_OtherUsersAndPlatforms(t *testing.T) {
+	a := NewPathAnonymizer("/home/alice", "", nil)
+
+	assert.Equal(t, "/home/user/shared", a.AnonymizeString("/home/bob/shared"))
+	assert.Equal(t, "/Users/user/Desktop", a.AnonymizeString("/Users/carol/Desktop"))
+	assert.Equal(t, `C:\Users\user\code`, a.AnonymizeString(`C:\Users\dave\code`))
+}
+
+func TestAnonymizeString_CustomPrefixes(t *testing.T) {
+	a := NewPathAnonymizer("/home/alice", "", []string{"/srv/clients/acme=$CLIENT", "/mnt/data"})
+
+	assert.Equal(t, "$CLIENT/build and $PREFIX2/raw", a.AnonymizeString("/srv/clients/acme/build and /mnt/data/raw"))
+}
+
+func TestAnonymizeString_EncodedProjectDirs(t *testing.T) {
+	a := NewPathAnonymizer("/home/alice", "/home/alice/work/app", nil)
+
+	assert.Equal(t, "~/.claude/projects/-project/s.jsonl", a.AnonymizeString("/home/alice/.claude/projects/-home-alice-work-app/s.jsonl"))
+	assert.Equal(t, "-home-user-other", a.AnonymizeString("-home-alice-other"))
+}
+
+func TestAnonymizeString_EncodedProjectDirsWithPunctuation(t *testing.T) {
+	a := NewPathAnonymizer("/home/alice", "/home/alice/my_app", nil)
+	assert.Equal(t, "projects/-project/s.jsonl", a.AnonymizeString("projects/-home-alice-my-app/s.jsonl"))
+
+	a = NewPathAnonymizer("/home/alice", "/home/alice/my app", nil)
+	assert.Equal(t, "projects/-project/s.jsonl", a.AnonymizeString("projects/-home-alice-my-app/s.jsonl"))
+}
+
+func TestAnonymizeMessages_ToolInputStaysValidJSON(t *testing.T) {
+	a := NewPathAnonymizer(`C:\Users\alice`, `C:\Users\alice\app`, nil)
+	msgs := []Message{{Role: "assistant", Blocks: []ContentBlock{{
+		Type:      "tool_use",
+		ToolInput: `{"file_path":"C:\\Users\\alice\\app\\main.go"}`,
+		Result:    &ContentBlock{Text: `read C:\Users\alice\app\main.go`},
+	}}}}
+
+	a.AnonymizeMessages(msgs)
+	assert.Equal(t, `{"file_path":"$PROJECT\\main.go"}`, msgs[0].Blocks[0].ToolInput)
+	assert.Equal(t, `read $PROJECT\main.go`, msgs[0].Blocks[0].Result.Text)
+}
+
+func TestAnonymizeMeta(t *testing.T) {
+	a := NewPathAnonymizer("/home/alice", "/home/alice/secret-client", nil)
+	meta := SessionMeta{Project: "secret-client", FirstPrompt: "fix /home/alice/secret-client/x.go"}
+	meta.Cwd = "/home/alice/secret-client/api"
+	meta.GitBranch = "backup/home/alice/notes"
+
+	a.AnonymizeMeta(&meta)
+	assert.Equal(t, "project", meta.Project)
+	assert.Equal(t, "fix $PROJECT/x.go", meta.FirstPrompt)
+	assert.Equal(t, "$PROJECT/api", meta.Cwd)
+	assert.Equal(t, "backup~/notes", meta.GitBranch)
+}
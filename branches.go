@@ -0,0 +1,188 @@
+package main
+
+// rowTree links session rows through their uuid/parentUuid fields. Editing a
+// prompt or rewinding in Claude Code starts a new child of an earlier row, so
+// a session file is a tree rather than a list.
+type rowTree struct {
+	rows     []sessionRow
+	parent   []int
+	children [][]int
+	roots    []int
+}
+
+func newRowTree(rows []sessionRow) *rowTree {
+	t := &rowTree{
+		rows:     rows,
+		parent:   make([]int, len(rows)),
+		children: make([][]int, len(rows)),
+	}
+
+	index := make(map[string]int, len(rows))
+	for i, row := range rows {
+		if row.UUID != "" {
+			index[row.UUID] = i
+		}
+	}
+
+	last := -1
+	for i, row := range rows {
+		t.parent[i] = -1
+		if row.UUID == "" && !isConversationRow(row) {
+			continue
+		}
+		if p, ok := index[row.ParentUUID.UUID]; ok && row.ParentUUID.UUID != "" {
+			t.parent[i] = p
+		} else if p, ok := index[row.LogicalUUID]; ok && row.LogicalUUID != "" {
+			t.parent[i] = p
+		} else if !row.ParentUUID.Null {
+			// Rows with missing links continue from whatever came before them.
+			t.parent[i] = last
+		}
+		if t.parent[i] >= 0 {
+			t.children[t.parent[i]] = append(t.children[t.parent[i]], i)
+		} else {
+			t.roots = append(t.roots, i)
+		}
+		last = i
+	}
+	return t
+}
+
+func isConversationRow(row sessionRow) bool {
+	return row.Type == "user" || row.Type == "assistant"
+}
+
+// pathTo returns the row indices from the root down to leaf.
+func (t *rowTree) pathTo(leaf int) []int {
+	var path []int
+	for i := leaf; i >= 0 && len(path) <= len(t.rows); i = t.parent[i] {
+		path = append(path, i)
+	}
+	for l, r := 0, len(path)-1; l < r; l, r = l+1, r-1 {
+		path[l], path[r] = path[r], path[l]
+	}
+	return path
+}
+
+// pathFrom returns the row indices from node down to leaf, which must be in
+// node's subtree.
+func (t *rowTree) pathFrom(node, leaf int) []int {
+	path := t.pathTo(leaf)
+	for i, n := range path {
+		if n == node {
+			return path[i:]
+		}
+	}
+	return path
+}
+
+// latestLeaf returns the most recently written conversation row in the
+// subtree rooted at node, or -1 if the subtree has none.
+func (t *rowTree) latestLeaf(node int) int {
+	best := -1
+	stack := []int{node}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if isConversationRow(t.rows[n]) && n > best {
+			best = n
+		}
+		stack = append(stack, t.children[n]...)
+	}
+	return best
+}
+
+func (t *rowTree) firstConversationRole(path []int) string {
+	for _, i := range path {
+		if isConversationRow(t.rows[i]) {
+			return t.rows[i].Type
+		}
+	}
+	return ""
+}
+
+// buildConversation follows the active branch (the one ending in the most
+// recently written row) and records every abandoned branch on the message
+// where it diverged.
+func buildConversation(rows []sessionRow, opts ParseOpts) []Message {
+	t := newRowTree(rows)
+	leaf := -1
+	for i := len(rows) - 1; i >= 0; i-- {
+		if isConversationRow(rows[i]) {
+			leaf = i
+			break
+		}
+	}
+	if leaf < 0 {
+		return nil
+	}
+	path := t.pathTo(leaf)
+	msgs := t.buildPath(path, opts)
+	if len(msgs) == 0 {
+		return msgs
+	}
+
+	// Editing the first prompt starts a new root, so the other roots are
+	// abandoned alternatives to the start of the conversation.
+	role := t.firstConversationRole(path)
+	for _, root := range t.roots {
+		if root == path[0] {
+			continue
+		}
+		leaf := t.latestLeaf(root)
+		if leaf < 0 {
+			continue
+		}
+		altPath := t.pathFrom(root, leaf)
+		if t.firstConversationRole(altPath) != role {
+			continue
+		}
+		if alt := t.buildPath(altPath, opts); len(alt) > 0 {
+			msgs[0].Branches = append(msgs[0].Branches, alt)
+		}
+	}
+	return msgs
+}
+
+func (t *rowTree) buildPath(path []int, opts ParseOpts) []Message {
+	pathRows := make([]sessionRow, len(path))
+	for i, n := range path {
+		pathRows[i] = t.rows[n]
+	}
+	msgs, starts := buildMessages(pathRows, opts)
+
+	for k := 0; k < len(path)-1; k++ {
+		next := path[k+1]
+		role := t.firstConversationRole(path[k+1:])
+		for _, child := range t.children[path[k]] {
+			if child == next {
+				continue
+			}
+			leaf := t.latestLeaf(child)
+			if leaf < 0 {
+				continue
+			}
+			altPath := t.pathFrom(child, leaf)
+			if t.firstConversationRole(altPath) != role {
+				continue
+			}
+			alt := t.buildPath(altPath, opts)
+			if len(alt) == 0 {
+				continue
+			}
+			if m := firstMessageFrom(starts, k+1); m >= 0 {
+				msgs[m].Branches = append(msgs[m].Branches, alt)
+			}
+		}
+	}
+	return msgs
+}
+
+func firstMessageFrom(starts []int, row int) int {
+	for i, s := range starts {
+		if s >= row {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// exporter holds the export options shared by single-session and batch
+// exports: path anonymization, redaction and the output format.
+type exporter struct {
+	render       func([]Message, SessionMeta, RenderOpts) (string, error)
+	ext          string
+	opts         RenderOpts
+	anonymize    bool
+	anonPrefixes []string
+	redactor     *Redactor
+	prices       PriceTable // if set, a usage summary is added to the output
+	images       ImageOpts
+	// privateImages is set when images are stripped because redaction and
+	// path anonymization only clean text; stripped counts them.
+	privateImages bool
+	stripped      atomic.Int64
+	// names redacts batch file names separately so they don't show up
+	// twice in the redaction report.
+	names *Redactor
+}
+
+// setRedaction turns on redaction with the built-in rules plus those in the
+// config file at configPath, if given.
+func (e *exporter) setRedaction(configPath string) error {
+	var cfg RedactConfig
+	if configPath != "" {
+		var err error
+		if cfg, err = LoadRedactConfig(configPath); err != nil {
+			return err
+		}
+	}
+	redactor, err := NewRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	names, err := NewRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	e.redactor, e.names = redactor, names
+	return nil
+}
+
+// reportImages notes on w how many images were left out to keep private
+// details in screenshots from being shared.
+func (e *exporter) reportImages(w io.Writer) {
+	n := e.stripped.Load()
+	if !e.privateImages || n == 0 {
+		return
+	}
+	noun := "images"
+	if n == 1 {
+		noun = "image"
+	}
+	fmt.Fprintf(w, "Left out %d %s, which redaction can't check; use --keep-images to include them\n", n, noun)
+}
+
+func (e *exporter) parseOpts() ParseOpts {
+	return ParseOpts{IncludeTools: e.opts.IncludeTools, IncludeThinking: e.opts.IncludeThinking}
+}
+
+// export anonymizes and redacts messages and meta in place, strips or
+// shrinks their images and renders them with the usage of tail.
+func (e *exporter) export(tail *sessionTail, messages []Message, meta SessionMeta, projectPath string) (string, error) {
+	e.stripped.Add(int64(processImages(messages, e.images)))
+	home, _ := os.UserHomeDir()
+	if e.anonymize {
+		anon := NewPathAnonymizer(home, projectPath, e.anonPrefixes)
+		anon.AnonymizeMessages(messages)
+		anon.AnonymizeMeta(&meta)
+	} else {
+		// The header shouldn't give away the username in the home path.
+		meta.Cwd = NewPathAnonymizer(home, "", nil).AnonymizeString(meta.Cwd)
+	}
+	if e.redactor != nil {
+		e.redactor.RedactMessages(messages)
+		meta.FirstPrompt = e.redactor.RedactString(meta.FirstPrompt)
+		meta.Cwd = e.redactor.RedactString(meta.Cwd)
+		meta.GitBranch = e.redactor.RedactString(meta.GitBranch)
+	}
+	opts := e.opts
+	if e.prices != nil {
+		usage := tail.Usage(e.prices)
+		opts.Usage = &usage
+	}
+	rendered, err := e.render(messages, meta, opts)
+	if err != nil {
+		return "", fmt.Errorf("rendering: %w", err)
+	}
+	return rendered, nil
+}
+
+// BatchResult reports what exportBatch wrote and which sessions failed.
+type BatchResult struct {
+	Written []string // output paths, in session order
+	Failed  []string // "<id>: <reason>"
+}
+
+// exportBatch writes each session to its own file in outDir, parsing up to
+// jobs sessions at a time. A session that fails is recorded in the result
+// and doesn't stop the others.
+func exportBatch(claudeDirs []string, outDir string, sessions []SessionSummary, e *exporter, jobs int) (BatchResult, error) {
+	var result BatchResult
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return result, fmt.Errorf("create output dir: %w", err)
+	}
+
+	names := batchFileNames(sessions, e)
+	errs := make([]error, len(sessions))
+	forEachParallel(len(sessions), jobs, func(i int) {
+		errs[i] = e.exportFile(claudeDirs, sessions[i], filepath.Join(outDir, names[i]))
+	})
+
+	for i, s := range sessions {
+		if errs[i] != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", s.ID, errs[i]))
+			continue
+		}
+		result.Written = append(result.Written, filepath.Join(outDir, names[i]))
+	}
+	return result, nil
+}
+
+// forEachParallel calls fn for 0 through n-1 on up to jobs goroutines and
+// waits for all calls to return.
+func forEachParallel(n, jobs int, fn func(i int)) {
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+func (e *exporter) exportFile(claudeDirs []string, s SessionSummary, path string) error {
+	sessionPath, err := FindSessionPath(claudeDirs, s.ID)
+	if err != nil {
+		return err
+	}
+	tail := newSessionTail(sessionPath)
+	if _, err := tail.Poll(); err != nil {
+		return err
+	}
+	messages := tail.Messages(e.parseOpts())
+	if len(messages) == 0 {
+		return fmt.Errorf("no messages")
+	}
+	meta := summaryMeta(s, len(messages), e.opts.Location)
+	meta.SessionDetails = tail.Details()
+	rendered, err := e.export(tail, messages, meta, s.Project)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(rendered), 0644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	return nil
+}
+
+// batchFileNames names each session's output file after its start date and
+// first prompt, e.g. 2025-01-31-fix-the-login-redirect.html. When two
+// sessions would share a name, the later one in the list gets its short ID
+// appended.
+func batchFileNames(sessions []SessionSummary, e *exporter) []string {
+	names := make([]string, len(sessions))
+	used := make(map[string]bool, len(sessions))
+	for i, s := range sessions {
+		base := slugify(e.namePrompt(s))
+		if s.Timestamp != 0 {
+			base = time.UnixMilli(s.Timestamp).Format("2006-01-02") + "-" + base
+		}
+		if used[base] {
+			base += "-" + shortID(s.ID)
+		}
+		used[base] = true
+		names[i] = base + "." + e.ext
+	}
+	return names
+}
+
+// namePrompt returns the first prompt with the same anonymization and
+// redaction the exported page gets.
+func (e *exporter) namePrompt(s SessionSummary) string {
+	prompt := s.FirstPrompt
+	if e.anonymize {
+		home, _ := os.UserHomeDir()
+		prompt = NewPathAnonymizer(home, s.Project, e.anonPrefixes).AnonymizeString(prompt)
+	}
+	if e.names != nil {
+		prompt = e.names.RedactString(prompt)
+	}
+	return prompt
+}
+
+const maxSlugLen = 50
+
+// slugify lowercases s and joins its runs of letters and digits with
+// hyphens, cut to maxSlugLen runes.
+func slugify(s string) string {
+	var b strings.Builder
+	n := 0
+	pending := false
+	for _, r := range strings.ToLower(s) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			pending = b.Len() > 0
+			continue
+		}
+		if n >= maxSlugLen {
+			break
+		}
+		if pending {
+			if n+1 >= maxSlugLen {
+				break
+			}
+			b.WriteByte('-')
+			n++
+			pending = false
+		}
+		b.WriteRune(r)
+		n++
+	}
+	if b.Len() == 0 {
+		return "session"
+	}
+	return b.String()
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "fix-the-login-redirect", slugify("Fix the login *redirect*!"))
+	assert.Equal(t, "café-naïve-42", slugify("  Café, naïve 42?  "))
+	assert.Equal(t, "session", slugify("!!!"))
+	assert.Equal(t, "session", slugify(""))
+
+	long := slugify(strings.Repeat("word ", 30))
+	assert.LessOrEqual(t, len(long), maxSlugLen)
+	assert.False(t, strings.HasSuffix(long, "-"))
+}
+
+func TestBatchFileNames(t *testing.T) {
+	day := time.Date(2025, 1, 31, 12, 0, 0, 0, time.Local).UnixMilli()
+	sessions := []SessionSummary{
+		{ID: "aaaaaaaa-1111", FirstPrompt: "Fix the bug", Timestamp: day},
+		{ID: "bbbbbbbb-2222", FirstPrompt: "fix the bug.", Timestamp: day},
+		{ID: "cccc", FirstPrompt: "mail bob@example.com"},
+	}
+	redactor, err := NewRedactor(RedactConfig{})
+	require.NoError(t, err)
+
+	names := batchFileNames(sessions, &exporter{ext: "md", names: redactor})
+	assert.Equal(t, []string{
+		"2025-01-31-fix-the-bug.md",
+		"2025-01-31-fix-the-bug-bbbbbbbb.md",
+		"mail-redacted-email.md",
+	}, names)
+}
+
+func TestExporter_HeaderIsRedacted(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	tail, err := readSessionTail(strings.NewReader(`{"type":"user","uuid":"u1","message":{"role":"user","content":"hi"}}
+`))
+	require.NoError(t, err)
+	redactor, err := NewRedactor(RedactConfig{})
+	require.NoError(t, err)
+	e := &exporter{render: RenderJSON, redactor: redactor}
+
+	meta := SessionMeta{SessionID: "s1"}
+	meta.Cwd = filepath.Join(home, "work", "build01.corp")
+	meta.GitBranch = "deploy/db.internal"
+	out, err := e.export(tail, tail.Messages(ParseOpts{}), meta, "")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"cwd": "~/work/[REDACTED:internal-host]"`)
+	assert.Contains(t, out, `"git_branch": "deploy/[REDACTED:internal-host]"`)
+	assert.NotContains(t, out, home)
+}
+
+func TestExporter_ReportsImagesLeftOut(t *testing.T) {
+	msgs := []Message{{Role: "user", Blocks: []ContentBlock{
+		{Type: "image", MediaType: "image/png", Data: "aGk="},
+		{Type: "image", MediaType: "image/png", Data: "aGk="},
+	}}}
+	tail, err := readSessionTail(strings.NewReader(""))
+	require.NoError(t, err)
+	e := &exporter{render: RenderJSON, images: ImageOpts{Strip: true}, privateImages: true}
+
+	_, err = e.export(tail, msgs, SessionMeta{}, "")
+	require.NoError(t, err)
+	assert.Empty(t, msgs[0].Blocks[0].Data)
+	var buf strings.Builder
+	e.reportImages(&buf)
+	assert.Equal(t, "Left out 2 images, which redaction can't check; use --keep-images to include them\n", buf.String())
+
+	buf.Reset()
+	e.privateImages = false
+	e.reportImages(&buf)
+	assert.Empty(t, buf.String(), "--strip-images needs no note")
+}
+
+func TestExportBatch_ReportsFailuresAndContinues(t *testing.T) {
+	dir := writeSiteFixture(t)
+	out := filepath.Join(t.TempDir(), "out")
+	sessions := []SessionSummary{
+		{ID: "s1", FirstPrompt: "first task", Project: "/home/user/webapp"},
+		{ID: "gone", FirstPrompt: "lost session", Project: "/home/user/webapp"},
+		{ID: "s2", FirstPrompt: "second task", Project: "/home/user/webapp"},
+		{ID: "s3", FirstPrompt: "third task", Project: "/home/user/webapp"},
+	}
+
+	result, err := exportBatch([]string{dir}, out, sessions, &exporter{render: RenderMD, ext: "md"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(out, "first-task.md"),
+		filepath.Join(out, "second-task.md"),
+		filepath.Join(out, "third-task.md"),
+	}, result.Written)
+	require.Len(t, result.Failed, 1)
+	assert.Contains(t, result.Failed[0], "gone: ")
+
+	assert.Contains(t, readFile(t, filepath.Join(out, "second-task.md")), "hello from s2")
+	assert.NoFileExists(t, filepath.Join(out, "lost-session.md"))
+}
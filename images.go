@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// ImageOpts controls how images pasted into a session or returned by tools
+// end up in an export.
+type ImageOpts struct {
+	Strip   bool // drop image data, leaving a placeholder
+	MaxSize int  // if set, scale images down so neither side exceeds this many pixels
+}
+
+// processImages strips or downscales every image in msgs in place and
+// returns how many images it stripped.
+func processImages(msgs []Message, opts ImageOpts) int {
+	if !opts.Strip && opts.MaxSize <= 0 {
+		return 0
+	}
+	stripped := 0
+	walkBlocks(msgs, func(b *ContentBlock) {
+		if b.Type == "image" && processImage(b, opts) {
+			stripped++
+		}
+		for i := range b.Images {
+			if processImage(&b.Images[i], opts) {
+				stripped++
+			}
+		}
+	})
+	return stripped
+}
+
+// processImage strips or downscales b and reports whether it stripped it.
+func processImage(b *ContentBlock, opts ImageOpts) bool {
+	if opts.Strip {
+		had := b.Data != ""
+		b.Data = ""
+		return had
+	}
+	if data, mediaType, ok := downscaleImage(b.Data, opts.MaxSize); ok {
+		b.Data, b.MediaType = data, mediaType
+	}
+	return false
+}
+
+// maxDecodePixels caps the images downscaleImage decodes. Session files
+// aren't trusted input, and a small file can claim dimensions that would
+// take gigabytes to decode.
+const maxDecodePixels = 50_000_000
+
+// downscaleImage scales base64 image data down to fit in maxSize pixels and
+// re-encodes it, as JPEG if it was one and as PNG otherwise. It returns false
+// if the image already fits, can't be decoded (e.g. WebP), is larger than
+// maxDecodePixels or wouldn't get any smaller.
+func downscaleImage(data string, maxSize int) (string, string, bool) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", "", false
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil || cfg.Width <= maxSize && cfg.Height <= maxSize || cfg.Width*cfg.Height > maxDecodePixels {
+		return "", "", false
+	}
+	src, format, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return "", "", false
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w >= h {
+		w, h = maxSize, max(1, h*maxSize/w)
+	} else {
+		w, h = max(1, w*maxSize/h), maxSize
+	}
+
+	var buf bytes.Buffer
+	mediaType := "image/png"
+	if format == "jpeg" {
+		mediaType = "image/jpeg"
+		err = jpeg.Encode(&buf, scaleImage(src, w, h), &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, scaleImage(src, w, h))
+	}
+	if err != nil || buf.Len() >= len(raw) {
+		return "", "", false
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), mediaType, true
+}
+
+// scaleImage shrinks src to w×h pixels, averaging the source pixels that
+// fall into each destination pixel.
+func scaleImage(src image.Image, w, h int) *image.RGBA {
+	pixel := pixelReader(src)
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := range w {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := pixel(sx, sy)
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			p := dst.Pix[dst.PixOffset(x, y):]
+			p[0], p[1], p[2], p[3] = uint8(r/n), uint8(g/n), uint8(bl/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// pixelReader returns a function that reads the alpha-premultiplied 8-bit
+// color of a pixel of src. The image types the decoders return are read
+// directly: going through At allocates a color per pixel, which is slow on
+// large screenshots.
+func pixelReader(src image.Image) func(x, y int) (r, g, b, a uint32) {
+	switch img := src.(type) {
+	case *image.RGBA:
+		return func(x, y int) (r, g, b, a uint32) {
+			p := img.Pix[img.PixOffset(x, y):]
+			return uint32(p[0]), uint32(p[1]), uint32(p[2]), uint32(p[3])
+		}
+	case *image.NRGBA:
+		return func(x, y int) (r, g, b, a uint32) {
+			p := img.Pix[img.PixOffset(x, y):]
+			a = uint32(p[3])
+			return uint32(p[0]) * a / 0xff, uint32(p[1]) * a / 0xff, uint32(p[2]) * a / 0xff, a
+		}
+	case *image.YCbCr:
+		return func(x, y int) (r, g, b, a uint32) {
+			ci := img.COffset(x, y)
+			cr, cg, cb := color.YCbCrToRGB(img.Y[img.YOffset(x, y)], img.Cb[ci], img.Cr[ci])
+			return uint32(cr), uint32(cg), uint32(cb), 0xff
+		}
+	}
+	return func(x, y int) (r, g, b, a uint32) {
+		r, g, b, a = src.At(x, y).RGBA()
+		return r >> 8, g >> 8, b >> 8, a >> 8
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodePNG returns a base64 PNG of random noise, which doesn't compress, so
+// a smaller version of it is always a smaller file.
+func encodePNG(t *testing.T, w, h int) string {
+	t.Helper()
+	rng := rand.New(rand.NewPCG(1, 2))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{R: uint8(rng.UintN(256)), G: uint8(rng.UintN(256)), B: uint8(rng.UintN(256)), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func decodeSize(t *testing.T, data string) (int, int) {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(data)
+	require.NoError(t, err)
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	require.NoError(t, err)
+	return cfg.Width, cfg.Height
+}
+
+func TestDownscaleImage(t *testing.T) {
+	data, mediaType, ok := downscaleImage(encodePNG(t, 400, 100), 80)
+	require.True(t, ok)
+	assert.Equal(t, "image/png", mediaType)
+	w, h := decodeSize(t, data)
+	assert.Equal(t, 80, w)
+	assert.Equal(t, 20, h)
+
+	_, _, ok = downscaleImage(encodePNG(t, 40, 30), 80)
+	assert.False(t, ok, "images that fit are left alone")
+	_, _, ok = downscaleImage("not base64!", 80)
+	assert.False(t, ok)
+}
+
+func TestDownscaleImage_SkipsHugeDimensions(t *testing.T) {
+	raw, err := base64.StdEncoding.DecodeString(encodePNG(t, 1, 1))
+	require.NoError(t, err)
+	// Claim 100000×100000 pixels in the IHDR chunk, which starts after the
+	// 8-byte signature and 8 bytes of chunk length and type.
+	binary.BigEndian.PutUint32(raw[16:], 100_000)
+	binary.BigEndian.PutUint32(raw[20:], 100_000)
+	binary.BigEndian.PutUint32(raw[29:], crc32.ChecksumIEEE(raw[12:29]))
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	require.NoError(t, err)
+	require.Equal(t, 100_000, cfg.Width)
+
+	_, _, ok := downscaleImage(base64.StdEncoding.EncodeToString(raw), 80)
+	assert.False(t, ok)
+}
+
+// opaqueImage hides the concrete type of an image, so scaleImage has to
+// read it through At.
+type opaqueImage struct{ image.Image }
+
+func TestScaleImage_DirectReadsMatchAt(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	rect := image.Rect(0, 0, 64, 48)
+	rgba, nrgba := image.NewRGBA(rect), image.NewNRGBA(rect)
+	ycbcr := image.NewYCbCr(rect, image.YCbCrSubsampleRatio420)
+	for _, pix := range [][]byte{rgba.Pix, nrgba.Pix, ycbcr.Y, ycbcr.Cb, ycbcr.Cr} {
+		for i := range pix {
+			pix[i] = uint8(rng.UintN(256))
+		}
+	}
+	// RGBA is premultiplied, so no channel may exceed alpha.
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		for c := range 3 {
+			rgba.Pix[i+c] = min(rgba.Pix[i+c], rgba.Pix[i+3])
+		}
+	}
+
+	for _, src := range []image.Image{rgba, nrgba, ycbcr} {
+		got := scaleImage(src, 10, 7)
+		want := scaleImage(opaqueImage{src}, 10, 7)
+		for i := range want.Pix {
+			assert.InDelta(t, want.Pix[i], got.Pix[i], 1, "%T byte %d", src, i)
+		}
+	}
+}
+
+func TestProcessImages(t *testing.T) {
+	big := encodePNG(t, 300, 300)
+	msgs := func() []Message {
+		return []Message{
+			{Role: "user", Blocks: []ContentBlock{{Type: "image", MediaType: "image/png", Data: big}}},
+			{Role: "assistant", Blocks: []ContentBlock{{Type: "tool_use", Result: &ContentBlock{
+				Type: "tool_result", Images: []ContentBlock{{Type: "image", MediaType: "image/png", Data: big}},
+			}}}},
+		}
+	}
+
+	stripped := msgs()
+	assert.Equal(t, 2, processImages(stripped, ImageOpts{Strip: true}))
+	assert.Empty(t, stripped[0].Blocks[0].Data)
+	assert.Empty(t, stripped[1].Blocks[0].Result.Images[0].Data)
+	assert.Equal(t, "image/png", stripped[0].Blocks[0].MediaType)
+
+	shrunk := msgs()
+	assert.Zero(t, processImages(shrunk, ImageOpts{MaxSize: 50}))
+	w, h := decodeSize(t, shrunk[0].Blocks[0].Data)
+	assert.Equal(t, 50, w)
+	assert.Equal(t, 50, h)
+	w, _ = decodeSize(t, shrunk[1].Blocks[0].Result.Images[0].Data)
+	assert.Equal(t, 50, w)
+
+	kept := msgs()
+	processImages(kept, ImageOpts{})
+	assert.Equal(t, big, kept[0].Blocks[0].Data)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// IndexEntry is one session listed on an index page.
+type IndexEntry struct {
+	Title        string
+	Project      string
+	Date         string
+	MessageCount int
+	URL          string
+}
+
+// IndexFilter holds the current values of the filter form shown on the
+// index page served by `serve`. Static indexes have no form.
+type IndexFilter struct {
+	Project         string
+	Sort            string // "newest" or "oldest"
+	IncludeTools    bool
+	IncludeThinking bool
+	Theme           string // theme requested in the URL, kept across submits
+}
+
+type IndexPage struct {
+	Title   string
+	Entries []IndexEntry
+	Theme   string
+	Filter  *IndexFilter
+}
+
+func RenderIndex(page IndexPage) (string, error) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		return "", err
+	}
+	if page.Theme == "" {
+		page.Theme = "dark"
+	}
+	if _, err := themeChromaCSS(page.Theme); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "index", page); err != nil {
+		return "", fmt.Errorf("execute template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// indexDate formats a start time for the index in loc; nil means local.
+func indexDate(ms int64, loc *time.Location) string {
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.UnixMilli(ms).In(loc).Format("Jan 2, 2006 15:04")
+}
+
+const indexTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>{{.Title}}</title>
+{{template "favicon"}}
+<style>
+{{template "base-css" .}}
+.session-meta{max-width:var(--max-w);margin:0 auto;padding:32px 24px 0}
+.session-title{font-size:1.35rem;font-weight:600;letter-spacing:-.02em;margin-bottom:6px}
+.session-info{color:var(--text-tertiary);font-size:.78rem}
+.index-filter{max-width:var(--max-w);margin:20px auto 0;padding:0 24px;display:flex;flex-wrap:wrap;align-items:center;gap:10px;font-size:.78rem;color:var(--text-secondary)}
+.index-filter input[type=text],.index-filter select{background:var(--surface);border:1px solid var(--border);border-radius:var(--radius-sm);color:var(--text);padding:6px 10px;font:inherit}
+.index-filter input[type=text]{flex:1;min-width:160px}
+.index-filter label{display:flex;align-items:center;gap:5px;cursor:pointer}
+.index-filter button{background:var(--accent);border:none;border-radius:var(--radius-sm);color:#fff;padding:6px 14px;font:inherit;font-weight:600;cursor:pointer}
+.index-list{max-width:var(--max-w);margin:0 auto;padding:20px 24px 40px;list-style:none}
+.index-item a{display:block;padding:14px 16px;border:1px solid var(--border);border-radius:var(--radius);background:var(--surface);color:var(--text);text-decoration:none;margin-bottom:10px;transition:background .15s}
+.index-item a:hover{background:var(--surface-hover)}
+.index-item-title{display:block;font-weight:500;white-space:nowrap;overflow:hidden;text-overflow:ellipsis}
+.index-item-info{display:flex;gap:14px;flex-wrap:wrap;font-size:.75rem;color:var(--text-tertiary);margin-top:2px}
+.index-empty{color:var(--text-tertiary);font-size:.85rem}
+@media(max-width:640px){
+  html{font-size:14px}
+  .topbar-inner,.session-meta,.index-filter,.index-list,.footer{padding-left:16px;padding-right:16px}
+}
+</style>
+</head>
+<body>
+
+{{template "topbar" .}}
+
+<div class="session-meta">
+  <h1 class="session-title">{{.Title}}</h1>
+  <div class="session-info">{{len .Entries}} session{{if ne (len .Entries) 1}}s{{end}}</div>
+</div>
+
+{{with .Filter}}<form class="index-filter" method="get" action="/">
+  <input type="text" name="project" value="{{.Project}}" placeholder="Filter by project">
+  <select name="sort">
+    <option value="newest"{{if ne .Sort "oldest"}} selected{{end}}>Newest first</option>
+    <option value="oldest"{{if eq .Sort "oldest"}} selected{{end}}>Oldest first</option>
+  </select>
+  <label><input type="checkbox" name="tools" value="1"{{if .IncludeTools}} checked{{end}}> Tools</label>
+  <label><input type="checkbox" name="thinking" value="1"{{if .IncludeThinking}} checked{{end}}> Thinking</label>
+  {{with .Theme}}<input type="hidden" name="theme" value="{{.}}">{{end}}
+  <button type="submit">Apply</button>
+</form>{{end}}
+
+<ul class="index-list">
+{{range .Entries}}  <li class="index-item"><a href="{{.URL}}">
+    <span class="index-item-title">{{if .Title}}{{.Title}}{{else}}Claude Conversation{{end}}</span>
+    <span class="index-item-info">{{if .Project}}<span>{{.Project}}</span>{{end}}{{if .Date}}<span>{{.Date}}</span>{{end}}{{if .MessageCount}}<span>{{.MessageCount}} message{{if ne .MessageCount 1}}s{{end}}</span>{{end}}</span>
+  </a></li>
+{{else}}  <li class="index-empty">No sessions found.</li>
+{{end}}</ul>
+
+{{template "footer"}}
+
+<script>
+{{template "theme-script" .}}</script>
+</body>
+</html>`
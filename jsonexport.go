@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONSchemaVersion is bumped whenever a field of the JSON export is removed
+// or changes meaning. Adding fields does not bump it.
+const JSONSchemaVersion = 1
+
+type jsonDocument struct {
+	SchemaVersion int           `json:"schema_version"`
+	Generator     string        `json:"generator"`
+	Session       jsonSession   `json:"session"`
+	Messages      []jsonMessage `json:"messages"`
+	Usage         *UsageReport  `json:"usage,omitempty"`
+}
+
+type jsonSession struct {
+	ID           string   `json:"id"`
+	Project      string   `json:"project,omitempty"`
+	Date         string   `json:"date,omitempty"`
+	FirstPrompt  string   `json:"first_prompt,omitempty"`
+	MessageCount int      `json:"message_count"`
+	Cwd          string   `json:"cwd,omitempty"`
+	GitBranch    string   `json:"git_branch,omitempty"`
+	Version      string   `json:"claude_code_version,omitempty"`
+	Models       []string `json:"models,omitempty"`
+}
+
+type jsonMessage struct {
+	Role      string          `json:"role"`
+	Timestamp string          `json:"timestamp,omitempty"`
+	Blocks    []jsonBlock     `json:"blocks"`
+	Branches  [][]jsonMessage `json:"branches,omitempty"`
+	Model     string          `json:"model,omitempty"`
+	Usage     *Usage          `json:"usage,omitempty"`
+}
+
+type jsonBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ToolName  string          `json:"tool_name,omitempty"`
+	ToolInput json.RawMessage `json:"tool_input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+	Result    *jsonBlock      `json:"result,omitempty"`
+	Sidechain []jsonMessage   `json:"sidechain,omitempty"`
+	MediaType string          `json:"media_type,omitempty"`
+	Data      string          `json:"data,omitempty"`
+	Images    []jsonBlock     `json:"images,omitempty"`
+}
+
+func RenderJSON(messages []Message, meta SessionMeta, opts RenderOpts) (string, error) {
+	doc := jsonDocument{
+		SchemaVersion: JSONSchemaVersion,
+		Generator:     "claude-share " + version,
+		Session: jsonSession{
+			ID:           meta.SessionID,
+			Project:      meta.Project,
+			Date:         meta.Date,
+			FirstPrompt:  meta.FirstPrompt,
+			MessageCount: meta.MessageCount,
+			Cwd:          meta.Cwd,
+			GitBranch:    meta.GitBranch,
+			Version:      meta.Version,
+			Models:       meta.Models,
+		},
+		Messages: toJSONMessages(messages),
+		Usage:    opts.Usage,
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode json: %w", err)
+	}
+	return string(out) + "\n", nil
+}
+
+func toJSONMessages(messages []Message) []jsonMessage {
+	result := make([]jsonMessage, 0, len(messages))
+	for _, msg := range messages {
+		jm := jsonMessage{
+			Role:      msg.Role,
+			Timestamp: msg.Timestamp,
+			Blocks:    make([]jsonBlock, 0, len(msg.Blocks)),
+			Model:     msg.Model,
+			Usage:     msg.Usage,
+		}
+		for _, b := range msg.Blocks {
+			jm.Blocks = append(jm.Blocks, toJSONBlock(b))
+		}
+		for _, alt := range msg.Branches {
+			jm.Branches = append(jm.Branches, toJSONMessages(alt))
+		}
+		result = append(result, jm)
+	}
+	return result
+}
+
+func toJSONBlock(b ContentBlock) jsonBlock {
+	jb := jsonBlock{
+		Type:      b.Type,
+		Text:      b.Text,
+		ToolName:  b.ToolName,
+		ToolUseID: b.ToolUseID,
+		IsError:   b.IsError,
+		MediaType: b.MediaType,
+		Data:      b.Data,
+	}
+	for _, img := range b.Images {
+		jb.Images = append(jb.Images, toJSONBlock(img))
+	}
+	if b.ToolInput != "" {
+		if json.Valid([]byte(b.ToolInput)) {
+			jb.ToolInput = json.RawMessage(b.ToolInput)
+		} else {
+			jb.ToolInput, _ = json.Marshal(b.ToolInput)
+		}
+	}
+	if b.Result != nil {
+		r := toJSONBlock(*b.Result)
+		jb.Result = &r
+	}
+	if len(b.Sidechain) > 0 {
+		jb.Sidechain = toJSONMessages(b.Sidechain)
+	}
+	return jb
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeExport(t *testing.T, messages []Message, meta SessionMeta) map[string]any {
+	t.Helper()
+	out, err := RenderJSON(messages, meta, RenderOpts{})
+	require.NoError(t, err)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &doc))
+	return doc
+}
+
+func TestRenderJSON_SchemaAndSession(t *testing.T) {
+	meta := SessionMeta{SessionID: "abc", Project: "proj", Date: "Jan 1, 2025", MessageCount: 2, FirstPrompt: "Hello"}
+	doc := decodeExport(t, []Message{userMsg("Hello"), assistantMsg("Hi")}, meta)
+
+	assert.Equal(t, float64(JSONSchemaVersion), doc["schema_version"])
+	assert.Contains(t, doc["generator"], "claude-share")
+	assert.Equal(t, map[string]any{
+		"id":            "abc",
+		"project":       "proj",
+		"date":          "Jan 1, 2025",
+		"first_prompt":  "Hello",
+		"message_count": float64(2),
+	}, doc["session"])
+
+	msgs := doc["messages"].([]any)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "user", msgs[0].(map[string]any)["role"])
+	assert.Equal(t, []any{map[string]any{"type": "text", "text": "Hi"}}, msgs[1].(map[string]any)["blocks"])
+}
+
+func TestRenderJSON_SessionDetails(t *testing.T) {
+	meta := SessionMeta{SessionID: "abc", SessionDetails: SessionDetails{
+		Cwd: "/home/user/webapp", GitBranch: "main", Version: "1.0.43", Models: []string{"claude-opus-4-5"},
+	}}
+	doc := decodeExport(t, []Message{userMsg("Hello")}, meta)
+
+	session := doc["session"].(map[string]any)
+	assert.Equal(t, "/home/user/webapp", session["cwd"])
+	assert.Equal(t, "main", session["git_branch"])
+	assert.Equal(t, "1.0.43", session["claude_code_version"])
+	assert.Equal(t, []any{"claude-opus-4-5"}, session["models"])
+}
+
+func TestRenderJSON_ToolInputIsNestedJSON(t *testing.T) {
+	messages := []Message{{Role: "assistant", Blocks: []ContentBlock{
+		{Type: "tool_use", ToolName: "Read", ToolUseID: "toolu_1", ToolInput: `{"file_path":"/a"}`,
+			Result: &ContentBlock{Type: "tool_result", ToolUseID: "toolu_1", Text: "boom", IsError: true}},
+		{Type: "tool_use", ToolName: "Odd", ToolInput: `not json`},
+	}}}
+	doc := decodeExport(t, messages, stubMeta)
+
+	blocks := doc["messages"].([]any)[0].(map[string]any)["blocks"].([]any)
+	call := blocks[0].(map[string]any)
+	assert.Equal(t, map[string]any{"file_path": "/a"}, call["tool_input"])
+	assert.Equal(t, map[string]any{"type": "tool_result", "tool_use_id": "toolu_1", "text": "boom", "is_error": true}, call["result"])
+	assert.Equal(t, "not json", blocks[1].(map[string]any)["tool_input"])
+}
+
+func TestRenderJSON_BranchesAndSidechains(t *testing.T) {
+	edited := userMsg("Edited")
+	edited.Branches = [][]Message{{userMsg("Original")}}
+	messages := []Message{
+		{Role: "assistant", Blocks: []ContentBlock{{Type: "tool_use", ToolName: "Task", ToolInput: `{}`, Sidechain: []Message{assistantMsg("Sub")}}}},
+		edited,
+	}
+	doc := decodeExport(t, messages, stubMeta)
+
+	msgs := doc["messages"].([]any)
+	call := msgs[0].(map[string]any)["blocks"].([]any)[0].(map[string]any)
+	assert.Len(t, call["sidechain"], 1)
+	branches := msgs[1].(map[string]any)["branches"].([]any)
+	require.Len(t, branches, 1)
+	assert.Equal(t, "user", branches[0].([]any)[0].(map[string]any)["role"])
+}
+
+func TestRenderJSON_EmptyMessages(t *testing.T) {
+	doc := decodeExport(t, nil, stubMeta)
+	assert.Equal(t, []any{}, doc["messages"])
+}
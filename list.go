@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listEntry is a history session plus what a scan of its file adds.
+type listEntry struct {
+	SessionSummary
+	SessionFileInfo
+}
+
+type listJSON struct {
+	ID           string `json:"id"`
+	Project      string `json:"project"`
+	FirstPrompt  string `json:"first_prompt"`
+	Timestamp    string `json:"timestamp"`
+	Path         string `json:"path,omitempty"`
+	Size         int64  `json:"size"`
+	MessageCount int    `json:"message_count"`
+	LastActivity string `json:"last_activity,omitempty"`
+}
+
+var listColumns = []string{"id", "project", "first_prompt", "timestamp", "path", "size", "message_count", "last_activity"}
+
+// listFilter selects sessions from history. Zero fields match everything.
+type listFilter struct {
+	Project string
+	Since   time.Time
+	Until   time.Time
+	Grep    *regexp.Regexp
+}
+
+func (f listFilter) match(s SessionSummary) bool {
+	if f.Project != "" && !strings.Contains(strings.ToLower(s.Project), strings.ToLower(f.Project)) {
+		return false
+	}
+	ts := time.UnixMilli(s.Timestamp)
+	if !f.Since.IsZero() && ts.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !ts.Before(f.Until) {
+		return false
+	}
+	if f.Grep != nil && !f.Grep.MatchString(s.FirstPrompt) {
+		return false
+	}
+	return true
+}
+
+// listSortNeedsScan reports whether sorting by key needs file details.
+func listSortNeedsScan(key string) bool {
+	return key == "size" || key == "messages"
+}
+
+// sortListEntries orders entries by key: date newest first, project
+// alphabetically, size and messages largest first. reverse flips the order.
+func sortListEntries(entries []listEntry, key string, reverse bool) error {
+	var less func(a, b listEntry) bool
+	switch key {
+	case "date":
+		less = func(a, b listEntry) bool { return a.Timestamp > b.Timestamp }
+	case "project":
+		less = func(a, b listEntry) bool { return strings.ToLower(a.Project) < strings.ToLower(b.Project) }
+	case "size":
+		less = func(a, b listEntry) bool { return a.Size > b.Size }
+	case "messages":
+		less = func(a, b listEntry) bool { return a.MessageCount > b.MessageCount }
+	default:
+		return fmt.Errorf("unknown sort key %q (want date, project, size or messages)", key)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+	return nil
+}
+
+// parseListFilter builds the filter for the --project, --since and --until
+// flags. Empty values don't filter.
+func parseListFilter(project, since, until string, now time.Time) (listFilter, error) {
+	filter := listFilter{Project: project}
+	var err error
+	if since != "" {
+		if filter.Since, err = parseListTime(since, now, false); err != nil {
+			return filter, fmt.Errorf("--since: %w", err)
+		}
+	}
+	if until != "" {
+		if filter.Until, err = parseListTime(until, now, true); err != nil {
+			return filter, fmt.Errorf("--until: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// parseListTime parses a --since/--until value: a date (2006-01-02), a date
+// and time (2006-01-02T15:04 or RFC 3339) in local time, or an age such as
+// 36h, 7d or 2w counted back from now. With end set, a bare date means the
+// end of that day.
+func parseListTime(s string, now time.Time, end bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if n := len(s); n > 1 {
+		if count, err := strconv.Atoi(s[:n-1]); err == nil && count >= 0 {
+			switch s[n-1] {
+			case 'h':
+				return now.Add(-time.Duration(count) * time.Hour), nil
+			case 'd':
+				return now.AddDate(0, 0, -count), nil
+			case 'w':
+				return now.AddDate(0, 0, -7*count), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want 2006-01-02, 2006-01-02T15:04 or an age like 7d)", s)
+}
+
+// scanListEntries adds file details to each session. Sessions whose file is
+// gone keep empty file fields, as do those whose file can't be read; the
+// latter are returned as "<id>: <reason>".
+func scanListEntries(claudeDirs []string, sessions []SessionSummary) ([]listEntry, []string, error) {
+	paths, err := sessionFiles(claudeDirs)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]listEntry, len(sessions))
+	var skipped []string
+	for i, s := range sessions {
+		entries[i].SessionSummary = s
+		if path, ok := paths[s.ID]; ok {
+			info, err := ScanSessionFile(path)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %v", s.ID, err))
+				continue
+			}
+			entries[i].SessionFileInfo = info
+		}
+	}
+	return entries, skipped, nil
+}
+
+func writeList(w io.Writer, entries []listEntry, format string) error {
+	switch format {
+	case "json":
+		out := make([]listJSON, len(entries))
+		for i, e := range entries {
+			out[i] = listJSON{
+				ID:           e.ID,
+				Project:      e.Project,
+				FirstPrompt:  e.FirstPrompt,
+				Timestamp:    formatListTime(time.UnixMilli(e.Timestamp)),
+				Path:         e.Path,
+				Size:         e.Size,
+				MessageCount: e.MessageCount,
+				LastActivity: formatListTime(e.LastActivity),
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(listColumns)
+		for _, e := range entries {
+			cw.Write(listRecord(e))
+		}
+		cw.Flush()
+		return cw.Error()
+	case "tsv":
+		// TSV has no quoting, so tabs and line breaks inside fields become
+		// spaces.
+		clean := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+		fmt.Fprintln(w, strings.Join(listColumns, "\t"))
+		for _, e := range entries {
+			rec := listRecord(e)
+			for i := range rec {
+				rec[i] = clean.Replace(rec[i])
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(rec, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (want text, json, csv or tsv)", format)
+}
+
+func listRecord(e listEntry) []string {
+	return []string{
+		e.ID,
+		e.Project,
+		e.FirstPrompt,
+		formatListTime(time.UnixMilli(e.Timestamp)),
+		e.Path,
+		strconv.FormatInt(e.Size, 10),
+		strconv.Itoa(e.MessageCount),
+		formatListTime(e.LastActivity),
+	}
+}
+
+func formatListTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testListEntries() []listEntry {
+	return []listEntry{
+		{
+			SessionSummary: SessionSummary{ID: "aaa", Project: "/home/user/app", FirstPrompt: "fix the \"build\",\tplease\nnow", Timestamp: 1735812000000},
+			SessionFileInfo: SessionFileInfo{
+				Path: "/c/projects/-home-user-app/aaa.jsonl", Size: 1234, MessageCount: 5,
+				LastActivity: time.Date(2025, 1, 2, 11, 0, 0, 0, time.UTC),
+			},
+		},
+		{SessionSummary: SessionSummary{ID: "bbb", Project: "/home/user/app", Timestamp: 1735812000000}},
+	}
+}
+
+func TestWriteList_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeList(&buf, testListEntries(), "json"))
+
+	var out []map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out, 2)
+	assert.Equal(t, "aaa", out[0]["id"])
+	assert.Equal(t, "fix the \"build\",\tplease\nnow", out[0]["first_prompt"])
+	assert.Equal(t, "2025-01-02T10:00:00Z", out[0]["timestamp"])
+	assert.Equal(t, "2025-01-02T11:00:00Z", out[0]["last_activity"])
+	assert.Equal(t, float64(1234), out[0]["size"])
+	assert.Equal(t, float64(5), out[0]["message_count"])
+	assert.NotContains(t, out[1], "path")
+}
+
+func TestWriteList_CSVRoundTrips(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeList(&buf, testListEntries(), "csv"))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, listColumns, records[0])
+	assert.Equal(t, "fix the \"build\",\tplease\nnow", records[1][2])
+	assert.Equal(t, "1234", records[1][5])
+}
+
+func TestWriteList_TSVOneLinePerSession(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeList(&buf, testListEntries(), "tsv"))
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	fields := strings.Split(lines[1], "\t")
+	require.Len(t, fields, len(listColumns))
+	assert.Equal(t, `fix the "build", please now`, fields[2])
+}
+
+func TestWriteList_UnknownFormat(t *testing.T) {
+	assert.Error(t, writeList(&bytes.Buffer{}, nil, "xml"))
+}
+
+func TestScanListEntries_AddsFileDetails(t *testing.T) {
+	dir := writeServeFixture(t)
+	sessions, err := ParseHistory([]string{dir})
+	require.NoError(t, err)
+
+	entries, skipped, err := scanListEntries([]string{dir}, sessions)
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "s2", entries[0].ID)
+	assert.Empty(t, entries[0].Path)
+	assert.Equal(t, "s1", entries[1].ID)
+	assert.Equal(t, 2, entries[1].MessageCount)
+	assert.NotEmpty(t, entries[1].Path)
+}
+
+func TestScanListEntries_SkipsUnreadableFiles(t *testing.T) {
+	dir := writeServeFixture(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "projects", "-home-user-cli", "s2.jsonl"), 0o755))
+	sessions, err := ParseHistory([]string{dir})
+	require.NoError(t, err)
+
+	entries, skipped, err := scanListEntries([]string{dir}, sessions)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Empty(t, entries[0].Path)
+	assert.Equal(t, 2, entries[1].MessageCount)
+	require.Len(t, skipped, 1)
+	assert.Contains(t, skipped[0], "s2: ")
+}
+
+func TestListFilter_Match(t *testing.T) {
+	s := SessionSummary{Project: "/home/user/WebApp", FirstPrompt: "Fix the login bug", Timestamp: time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC).UnixMilli()}
+
+	assert.True(t, listFilter{}.match(s))
+	assert.True(t, listFilter{Project: "webapp"}.match(s))
+	assert.False(t, listFilter{Project: "cli"}.match(s))
+	assert.True(t, listFilter{Since: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)}.match(s))
+	assert.False(t, listFilter{Since: time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)}.match(s))
+	assert.True(t, listFilter{Until: time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)}.match(s))
+	assert.False(t, listFilter{Until: time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)}.match(s))
+	assert.True(t, listFilter{Grep: regexp.MustCompile(`(?i)login\s+BUG`)}.match(s))
+	assert.False(t, listFilter{Grep: regexp.MustCompile(`(?i)logout`)}.match(s))
+}
+
+func TestParseListTime(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		in   string
+		end  bool
+		want time.Time
+	}{
+		{"2025-03-01", false, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-03-01", true, time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"2025-03-01T09:30", true, time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{"2025-03-01T09:30:00+02:00", false, time.Date(2025, 3, 1, 7, 30, 0, 0, time.UTC)},
+		{"36h", false, now.Add(-36 * time.Hour)},
+		{"7d", false, time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)},
+		{"2w", false, time.Date(2025, 2, 24, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		got, err := parseListTime(c.in, now, c.end)
+		require.NoError(t, err, c.in)
+		assert.True(t, c.want.Equal(got), "%s: got %s", c.in, got)
+	}
+
+	for _, bad := range []string{"yesterday", "7y", "d", "-3d"} {
+		_, err := parseListTime(bad, now, false)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseListFilter(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	f, err := parseListFilter("app", "7d", "2025-03-09", now)
+	require.NoError(t, err)
+	assert.Equal(t, "app", f.Project)
+	assert.Equal(t, now.AddDate(0, 0, -7), f.Since)
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), f.Until)
+
+	f, err = parseListFilter("", "", "", now)
+	require.NoError(t, err)
+	assert.Zero(t, f)
+
+	_, err = parseListFilter("", "", "soon", now)
+	assert.ErrorContains(t, err, "--until: ")
+}
+
+func TestSortListEntries(t *testing.T) {
+	entries := []listEntry{
+		{SessionSummary: SessionSummary{ID: "a", Project: "/b", Timestamp: 1}, SessionFileInfo: SessionFileInfo{Size: 10, MessageCount: 3}},
+		{SessionSummary: SessionSummary{ID: "b", Project: "/a", Timestamp: 3}, SessionFileInfo: SessionFileInfo{Size: 30, MessageCount: 1}},
+		{SessionSummary: SessionSummary{ID: "c", Project: "/C", Timestamp: 2}, SessionFileInfo: SessionFileInfo{Size: 20, MessageCount: 2}},
+	}
+	ids := func() string {
+		var s string
+		for _, e := range entries {
+			s += e.ID
+		}
+		return s
+	}
+
+	cases := []struct {
+		key     string
+		reverse bool
+		want    string
+	}{
+		{"date", false, "bca"},
+		{"date", true, "acb"},
+		{"project", false, "bac"},
+		{"size", false, "bca"},
+		{"messages", false, "acb"},
+		{"messages", true, "bca"},
+	}
+	for _, c := range cases {
+		require.NoError(t, sortListEntries(entries, c.key, c.reverse))
+		assert.Equal(t, c.want, ids(), "%s reverse=%v", c.key, c.reverse)
+	}
+	assert.Error(t, sortListEntries(entries, "name", false))
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -14,6 +22,8 @@ var version = "dev"
 
 func main() {
 	showVersion := flag.Bool("version", false, "Show version")
+	var dirFlags stringList
+	flag.Var(&dirFlags, "claude-dir", "Claude data directory (repeatable)")
 	flag.Usage = printUsage
 	flag.Parse()
 
@@ -27,13 +37,23 @@ func main() {
 		os.Exit(1)
 	}
 
-	claudeDir := filepath.Join(os.Getenv("HOME"), ".claude")
+	claudeDirs := resolveClaudeDirs(dirFlags, os.Getenv("CLAUDE_CONFIG_DIR"))
 
 	switch flag.Arg(0) {
 	case "list":
-		cmdList(claudeDir, flag.Args()[1:])
+		cmdList(claudeDirs, flag.Args()[1:])
 	case "export":
-		cmdExport(claudeDir, flag.Args()[1:])
+		cmdExport(claudeDirs, flag.Args()[1:])
+	case "search":
+		cmdSearch(claudeDirs, flag.Args()[1:])
+	case "serve":
+		cmdServe(claudeDirs, flag.Args()[1:])
+	case "site":
+		cmdSite(claudeDirs, flag.Args()[1:])
+	case "stats":
+		cmdStats(claudeDirs, flag.Args()[1:])
+	case "report":
+		cmdReport(claudeDirs, flag.Args()[1:])
 	case "version":
 		fmt.Println(version)
 	case "help":
@@ -45,33 +65,98 @@ func main() {
 	}
 }
 
+// resolveClaudeDirs picks the Claude data directories to read, earlier ones
+// taking precedence: --claude-dir flags if given, else CLAUDE_CONFIG_DIR,
+// else ~/.claude. Like in Claude Code, CLAUDE_CONFIG_DIR names a single
+// directory.
+func resolveClaudeDirs(flags []string, env string) []string {
+	if len(flags) > 0 {
+		return flags
+	}
+	if env != "" {
+		return []string{env}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return []string{filepath.Join(home, ".claude")}
+}
+
 func printUsage() {
 	fmt.Fprintln(os.Stderr, `Usage:
   claude-share [global options] command [command options]
 
 Global options:
-  --version    Show version
-  --help       Show this help
+  --claude-dir dir  Claude data directory; repeat to merge several
+                    (default: $CLAUDE_CONFIG_DIR, else ~/.claude)
+  --version         Show version
+  --help            Show this help
 
 Commands:
   list         List all sessions
-  export       Export a session to HTML
+  export       Export a session to HTML, Markdown or JSON
+  search       Search the text of all sessions
+  serve        Browse and render sessions in the browser
+  site         Export a project's sessions as a static website
+  stats        Show a session's token usage and estimated cost
+  report       Summarize usage across sessions by project, time, model and tool
 
 Examples:
   claude-share list --project myproject
-  claude-share export abc123 -o output.html`)
+  claude-share list --since 7d --sort messages --limit 10
+  claude-share list --format json | jq '.[].id'
+  claude-share export abc123 -o output.html
+  claude-share export latest --project myproject -o output.html
+  claude-share export --file session.jsonl -o output.html
+  claude-share export abc123 --format md -o output.md
+  claude-share export --project myproject --since 30d --out-dir exports/
+  claude-share search "race condition" --tools
+  claude-share serve --addr :8080
+  claude-share site --project myproject -o site/
+  claude-share stats latest
+  claude-share report --since 30d --period week --format html -o usage.html`)
 }
 
-func cmdList(claudeDir string, args []string) {
+func cmdList(claudeDirs []string, args []string) {
 	fs := flag.NewFlagSet("list", flag.ExitOnError)
 	project := fs.String("project", "", "Filter sessions by project path substring")
+	format := fs.String("format", "text", "Output format: text, json, csv or tsv")
+	since := fs.String("since", "", "Only sessions started at or after this date, time or age (e.g. 2025-01-31, 7d)")
+	until := fs.String("until", "", "Only sessions started before the end of this date, or before this time or age")
+	grep := fs.String("grep", "", "Only sessions whose first prompt matches this regular expression (case-insensitive)")
+	sortBy := fs.String("sort", "date", "Sort by date, project, size or messages")
+	limit := fs.Int("limit", 0, "Show at most N sessions")
+	reverse := fs.Bool("reverse", false, "Reverse the sort order")
 	fs.Parse(args)
 
-	sessions, err := ParseHistory(claudeDir)
+	switch *format {
+	case "text", "json", "csv", "tsv":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text, json, csv or tsv)\n", *format)
+		os.Exit(1)
+	}
+
+	filter, err := parseListFilter(*project, *since, *until, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *grep != "" {
+		if filter.Grep, err = regexp.Compile("(?i)" + *grep); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --grep: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	sessions, err := ListSessions(claudeDirs)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			historyPath := filepath.Join(claudeDir, "history.jsonl")
-			fmt.Fprintf(os.Stderr, "Error: Could not find Claude history at %s\n", historyPath)
+			var historyPaths []string
+			for _, dir := range claudeDirs {
+				historyPaths = append(historyPaths, filepath.Join(dir, "history.jsonl"))
+			}
+			fmt.Fprintf(os.Stderr, "Error: Could not find Claude history at %s\n", strings.Join(historyPaths, ", "))
 			fmt.Fprintln(os.Stderr, "Hint: Make sure you've used Claude Code at least once")
 		} else {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -79,11 +164,47 @@ func cmdList(claudeDir string, args []string) {
 		os.Exit(1)
 	}
 
-	filter := *project
+	var matched []SessionSummary
 	for _, s := range sessions {
-		if filter != "" && !strings.Contains(strings.ToLower(s.Project), strings.ToLower(filter)) {
-			continue
+		if filter.match(s) {
+			matched = append(matched, s)
+		}
+	}
+
+	var entries []listEntry
+	if *format != "text" || listSortNeedsScan(*sortBy) {
+		var skipped []string
+		entries, skipped, err = scanListEntries(claudeDirs, matched)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, s := range skipped {
+			fmt.Fprintf(os.Stderr, "Skipped %s\n", s)
 		}
+	} else {
+		entries = make([]listEntry, len(matched))
+		for i, s := range matched {
+			entries[i].SessionSummary = s
+		}
+	}
+	if err := sortListEntries(entries, *sortBy, *reverse); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[:*limit]
+	}
+
+	if *format != "text" {
+		if err := writeList(os.Stdout, entries, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	for _, s := range entries {
 		ts := time.UnixMilli(s.Timestamp).Format("2006-01-02 15:04")
 		prompt := s.FirstPrompt
 		if len(prompt) > 60 {
@@ -97,87 +218,755 @@ func cmdList(claudeDir string, args []string) {
 	}
 }
 
-func cmdExport(claudeDir string, args []string) {
-	var flagArgs []string
-	var positional []string
-	for i := 0; i < len(args); i++ {
-		if strings.HasPrefix(args[i], "-") {
-			flagArgs = append(flagArgs, args[i])
-			if args[i] == "-o" && i+1 < len(args) {
-				i++
-				flagArgs = append(flagArgs, args[i])
-			}
-		} else {
-			positional = append(positional, args[i])
-		}
-	}
-
+func cmdExport(claudeDirs []string, args []string) {
 	fs := flag.NewFlagSet("export", flag.ExitOnError)
 	output := fs.String("o", "", "Output file (default: stdout)")
+	outDir := fs.String("out-dir", "", "Export every given or matching session into this directory")
+	format := fs.String("format", "html", "Output format: html, md or json")
 	includeTools := fs.Bool("include-tools", false, "Include tool calls and results")
 	includeThinking := fs.Bool("include-thinking", false, "Include thinking blocks")
-	fs.Parse(flagArgs)
+	theme := fs.String("theme", "dark", "HTML color theme: dark, light or auto")
+	redact := fs.Bool("redact", false, "Replace secrets and emails with placeholders")
+	redactConfig := fs.String("redact-config", "", "JSON file with extra redaction patterns (implies --redact)")
+	anonymize := fs.Bool("anonymize-paths", false, "Replace home, project and other local paths with neutral tokens")
+	var anonPrefixes stringList
+	fs.Var(&anonPrefixes, "anonymize-prefix", "Extra path prefix to anonymize, as path or path=TOKEN (repeatable, implies --anonymize-paths)")
+	usage := fs.Bool("usage", false, "Add a token usage and cost summary to the header")
+	pricesPath := fs.String("prices", "", "JSON file with model prices per million tokens for --usage (implies --usage)")
+	watch := fs.Bool("watch", false, "Keep running and rewrite the output file as the session grows")
+	project := fs.String("project", "", "Only consider sessions from matching projects when resolving latest or exporting to --out-dir")
+	since := fs.String("since", "", "With --out-dir, only sessions started at or after this date, time or age")
+	until := fs.String("until", "", "With --out-dir, only sessions started before the end of this date, or before this time or age")
+	jobs := fs.Int("jobs", 4, "With --out-dir, number of sessions to export in parallel")
+	file := fs.String("file", "", "Export this session JSONL file instead of looking up a session ID")
+	tz := fs.String("tz", "Local", "Time zone for message times, e.g. UTC or Europe/Berlin")
+	stripImages := fs.Bool("strip-images", false, "Leave out pasted and tool-returned images, keeping a placeholder")
+	maxImageSize := fs.Int("max-image-size", 0, "Scale down images larger than this many pixels on either side")
+	keepImages := fs.Bool("keep-images", false, "Keep images with --redact or --anonymize-paths, which can't clean them")
+	positional := parseInterspersed(fs, args)
 
-	if len(positional) < 1 {
-		fmt.Fprintln(os.Stderr, "Error: session ID required")
-		fmt.Fprintln(os.Stderr, "Usage: claude-share export <session-id> [-o file] [--include-tools] [--include-thinking]")
+	batch := *outDir != "" || len(positional) > 1
+	if batch {
+		if err := checkBatchFlags(*output, *outDir, *file, positional, *watch, *jobs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if len(positional) < 1 && *file == "" || len(positional) > 0 && *file != "" {
+		fmt.Fprintln(os.Stderr, "Error: give either a session ID, --file path or - for stdin")
+		fmt.Fprintln(os.Stderr, "Usage: claude-share export <session-id|prefix|latest[~N]|-> [--file session.jsonl] [--project name] [-o file] [--format html|md|json] [--theme dark|light|auto] [--redact] [--redact-config file] [--anonymize-paths] [--anonymize-prefix path[=TOKEN]] [--include-tools] [--include-thinking] [--usage] [--prices file] [--tz zone] [--strip-images] [--max-image-size px] [--keep-images] [--watch]")
+		fmt.Fprintln(os.Stderr, "       claude-share export [session-id...] --out-dir dir [--project name] [--since when] [--until when] [--jobs N] [options]")
+		os.Exit(1)
+	} else if *since != "" || *until != "" {
+		fmt.Fprintln(os.Stderr, "Error: --since and --until need --out-dir")
 		os.Exit(1)
 	}
-	sessionID := positional[0]
 
-	sessionPath, err := FindSessionPath(claudeDir, sessionID)
+	e := &exporter{
+		ext: *format,
+		opts: RenderOpts{
+			IncludeTools:    *includeTools,
+			IncludeThinking: *includeThinking,
+			Theme:           *theme,
+		},
+		anonymize:    *anonymize || len(anonPrefixes) > 0,
+		anonPrefixes: anonPrefixes,
+	}
+	switch *format {
+	case "html":
+		e.render = RenderHTML
+	case "md", "markdown":
+		e.render, e.ext = RenderMD, "md"
+	case "json":
+		e.render = RenderJSON
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want html, md or json)\n", *format)
+		os.Exit(1)
+	}
+	requireTheme(*theme)
+	if *format != "html" && flagWasSet(fs, "theme") {
+		fmt.Fprintln(os.Stderr, "Error: --theme only applies to --format html")
+		os.Exit(1)
+	}
+	loc, err := time.LoadLocation(*tz)
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown time zone %q\n", *tz)
+		os.Exit(1)
+	}
+	e.opts.Location = loc
+	if e.images, err = imageOpts(*stripImages, *maxImageSize); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	opts := ParseOpts{
-		IncludeTools:    *includeTools,
-		IncludeThinking: *includeThinking,
+	if *watch && *output == "" {
+		fmt.Fprintln(os.Stderr, "Error: --watch needs an output file (-o)")
+		os.Exit(1)
 	}
-	messages, err := ParseSession(sessionPath, opts)
-	if err != nil {
+
+	if *usage || *pricesPath != "" {
+		if e.prices, err = loadPrices(*pricesPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *redact || *redactConfig != "" {
+		if err := e.setRedaction(*redactConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *keepImages && *stripImages {
+		fmt.Fprintln(os.Stderr, "Error: --keep-images and --strip-images can't be used together")
+		os.Exit(1)
+	}
+	if (e.redactor != nil || e.anonymize) && !*keepImages && !e.images.Strip {
+		// A screenshot can show the secrets and paths the text is
+		// cleaned of.
+		e.images, e.privateImages = ImageOpts{Strip: true}, true
+	}
+
+	if batch {
+		filter, err := parseListFilter(*project, *since, *until, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		exportToDir(claudeDirs, *outDir, positional, filter, e, *jobs)
+		return
+	}
+
+	var sessionID string
+	var tail *sessionTail
+	switch {
+	case *file != "":
+		tail = newSessionTail(*file)
+	case positional[0] == "-":
+		if *watch {
+			fmt.Fprintln(os.Stderr, "Error: --watch can't be used when reading from stdin")
+			os.Exit(1)
+		}
+		if tail, err = readSessionTail(os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		if sessionID, err = ResolveSessionID(claudeDirs, positional[0], *project); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		sessionPath, err := FindSessionPath(claudeDirs, sessionID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		tail = newSessionTail(sessionPath)
+	}
+	if _, err := tail.Poll(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing session: %v\n", err)
 		os.Exit(1)
 	}
+	if sessionID == "" {
+		sessionID = tail.Summary().ID
+	}
+	if sessionID == "" && *file != "" {
+		sessionID = strings.TrimSuffix(filepath.Base(*file), ".jsonl")
+	}
+
+	export := func() (string, int, error) {
+		messages := tail.Messages(e.parseOpts())
+		if len(messages) == 0 {
+			return "", 0, nil
+		}
+
+		meta, projectPath, err := sessionMeta(claudeDirs, tail, sessionID, len(messages), e.opts.Location)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not load session history: %v\n", err)
+		}
+		rendered, err := e.export(tail, messages, meta, projectPath)
+		if err != nil {
+			return "", 0, err
+		}
+		return rendered, len(messages), nil
+	}
 
-	if len(messages) == 0 {
+	rendered, count, err := export()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if count == 0 && !*watch {
 		fmt.Fprintln(os.Stderr, "No messages found in session")
 		os.Exit(1)
 	}
+	if e.redactor != nil {
+		e.redactor.Report(os.Stderr)
+	}
+	e.reportImages(os.Stderr)
 
-	sessions, err := ParseHistory(claudeDir)
+	if *output == "" {
+		fmt.Print(rendered)
+		return
+	}
+	if count > 0 {
+		if err := writeFileAtomic(*output, []byte(rendered)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Exported to %s\n", *output)
+	}
+	if !*watch {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Watching %s for changes (Ctrl-C to stop)\n", tail.path)
+	err = watchSession(ctx, tail, watchInterval, func() error {
+		rendered, count, err := export()
+		if err != nil || count == 0 {
+			return err
+		}
+		if err := writeFileAtomic(*output, []byte(rendered)); err != nil {
+			return fmt.Errorf("writing file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "%s Updated %s (%d messages)\n", time.Now().Format("15:04:05"), *output, count)
+		return nil
+	})
 	if err != nil {
-		if !os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Warning: could not load session history: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// checkBatchFlags rejects export options that don't combine with exporting
+// several sessions into outDir.
+func checkBatchFlags(output, outDir, file string, refs []string, watch bool, jobs int) error {
+	switch {
+	case outDir == "":
+		return fmt.Errorf("exporting several sessions needs an output directory (--out-dir)")
+	case output != "":
+		return fmt.Errorf("-o and --out-dir can't be used together")
+	case file != "" || slices.Contains(refs, "-"):
+		return fmt.Errorf("--out-dir exports session IDs, not --file or stdin")
+	case watch:
+		return fmt.Errorf("--watch can't be used with --out-dir")
+	case jobs < 1:
+		return fmt.Errorf("--jobs must be at least 1")
+	}
+	return nil
+}
+
+// exportToDir exports the sessions named by refs, or every session if refs
+// is empty, that match filter. Sessions that can't be resolved or exported
+// are reported at the end; the exit status is non-zero if any failed.
+func exportToDir(claudeDirs []string, outDir string, refs []string, filter listFilter, e *exporter, jobs int) {
+	sessions, err := ListSessions(claudeDirs)
+	if err != nil && (len(refs) == 0 || !errors.Is(err, os.ErrNotExist)) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var failed, filtered []string
+	var selected []SessionSummary
+	if len(refs) == 0 {
+		for _, s := range sessions {
+			if filter.match(s) {
+				selected = append(selected, s)
+			}
+		}
+	} else {
+		known := make(map[string]SessionSummary, len(sessions))
+		for _, s := range sessions {
+			known[s.ID] = s
+		}
+		seen := make(map[string]bool)
+		for _, ref := range refs {
+			id, err := ResolveSessionID(claudeDirs, ref, filter.Project)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", ref, err))
+				continue
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			s, ok := known[id]
+			if !ok {
+				// Sessions missing from the list have no start time there;
+				// read it from the file, or leave the filters to the export.
+				s = SessionSummary{ID: id}
+				if path, err := FindSessionPath(claudeDirs, id); err == nil {
+					if peeked, err := peekSession(path); err == nil {
+						s, ok = peeked, true
+						s.ID = id
+					}
+				}
+			}
+			if ok && !filter.match(s) {
+				filtered = append(filtered, ref)
+				continue
+			}
+			selected = append(selected, s)
 		}
 	}
-	meta := SessionMeta{SessionID: sessionID, MessageCount: len(messages)}
-	for _, s := range sessions {
-		if s.ID == sessionID {
-			meta.Project = filepath.Base(s.Project)
-			meta.Date = time.UnixMilli(s.Timestamp).Format("Jan 2, 2006")
-			meta.FirstPrompt = s.FirstPrompt
-			break
+	for _, ref := range filtered {
+		fmt.Fprintf(os.Stderr, "Skipped %s: does not match the filters\n", ref)
+	}
+	if len(selected) == 0 && len(failed) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no sessions match")
+		os.Exit(1)
+	}
+
+	var result BatchResult
+	if len(selected) > 0 {
+		if result, err = exportBatch(claudeDirs, outDir, selected, e, jobs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, f := range append(failed, result.Failed...) {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", f)
+	}
+	if e.redactor != nil {
+		e.redactor.Report(os.Stderr)
+	}
+	e.reportImages(os.Stderr)
+	fmt.Fprintf(os.Stderr, "Exported %d of %d sessions to %s\n", len(result.Written), len(selected)+len(failed), outDir)
+	if len(failed)+len(result.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// writeFileAtomic replaces path in one step so that a browser reloading the
+// file never sees it half-written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func cmdSearch(claudeDirs []string, args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	regex := fs.Bool("regex", false, "Treat the query as a regular expression")
+	caseSensitive := fs.Bool("case-sensitive", false, "Match case exactly")
+	tools := fs.Bool("tools", false, "Also search tool inputs and outputs")
+	color := fs.String("color", "auto", "Highlight matches: auto, always or never")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) < 1 {
+		fmt.Fprintln(os.Stderr, "Error: search query required")
+		fmt.Fprintln(os.Stderr, "Usage: claude-share search <query> [--regex] [--case-sensitive] [--tools] [--color auto|always|never]")
+		os.Exit(1)
+	}
+
+	result, err := SearchSessions(claudeDirs, SearchOpts{
+		Query:         strings.Join(positional, " "),
+		Regex:         *regex,
+		CaseSensitive: *caseSensitive,
+		IncludeTools:  *tools,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Skipped %s\n", skipped)
+	}
+	if len(result.Hits) == 0 {
+		fmt.Fprintln(os.Stderr, "No matches found")
+		os.Exit(1)
+	}
+
+	highlight := *color == "always"
+	if *color == "auto" {
+		if info, err := os.Stdout.Stat(); err == nil {
+			highlight = info.Mode()&os.ModeCharDevice != 0
 		}
 	}
+	for _, h := range result.Hits {
+		ts := h.Timestamp
+		if t, err := time.Parse(time.RFC3339, h.Timestamp); err == nil {
+			ts = t.Local().Format("2006-01-02 15:04")
+		}
+		snippet := h.Snippet
+		if highlight {
+			snippet = snippet[:h.Match[0]] + "\x1b[1;31m" + snippet[h.Match[0]:h.Match[1]] + "\x1b[0m" + snippet[h.Match[1]:]
+		}
+		fmt.Printf("%-38s  %-16s  %-20s  %-9s  %s\n", h.SessionID, ts, h.Project, h.Role, snippet)
+	}
+}
+
+func cmdServe(claudeDirs []string, args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	theme := fs.String("theme", "dark", "Default HTML color theme: dark, light or auto")
+	watch := fs.Bool("watch", false, "Reload open session pages as their sessions grow")
+	tz := fs.String("tz", "Local", "Time zone for message times, e.g. UTC or Europe/Berlin")
+	parseInterspersed(fs, args)
+
+	requireTheme(*theme)
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown time zone %q\n", *tz)
+		os.Exit(1)
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Serving sessions on http://%s\n", browseAddr(ln.Addr()))
+	if err := http.Serve(ln, newServer(claudeDirs, *theme, loc, *watch)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func cmdSite(claudeDirs []string, args []string) {
+	fs := flag.NewFlagSet("site", flag.ExitOnError)
+	project := fs.String("project", "", "Only include sessions whose project path contains this substring")
+	output := fs.String("o", "", "Output directory")
+	includeTools := fs.Bool("include-tools", false, "Include tool calls and results")
+	includeThinking := fs.Bool("include-thinking", false, "Include thinking blocks")
+	theme := fs.String("theme", "dark", "HTML color theme: dark, light or auto")
+	tz := fs.String("tz", "Local", "Time zone for message times, e.g. UTC or Europe/Berlin")
+	stripImages := fs.Bool("strip-images", false, "Leave out pasted and tool-returned images, keeping a placeholder")
+	maxImageSize := fs.Int("max-image-size", 0, "Scale down images larger than this many pixels on either side")
+	parseInterspersed(fs, args)
+
+	if *output == "" {
+		fmt.Fprintln(os.Stderr, "Error: output directory required")
+		fmt.Fprintln(os.Stderr, "Usage: claude-share site -o <dir> [--project name] [--theme dark|light|auto] [--tz zone] [--include-tools] [--include-thinking] [--strip-images] [--max-image-size px]")
+		os.Exit(1)
+	}
+	requireTheme(*theme)
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown time zone %q\n", *tz)
+		os.Exit(1)
+	}
+	images, err := imageOpts(*stripImages, *maxImageSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
-	htmlStr, err := RenderHTML(messages, meta, RenderOpts{
+	result, err := BuildSite(claudeDirs, *output, SiteOpts{
+		Project:         *project,
 		IncludeTools:    *includeTools,
 		IncludeThinking: *includeThinking,
+		Theme:           *theme,
+		Location:        loc,
+		Images:          images,
 	})
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Skipped %s\n", skipped)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error rendering: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Fprintf(os.Stderr, "Wrote %d pages to %s\n", result.Pages, filepath.Join(*output, "index.html"))
+}
 
-	if *output != "" {
-		if err := os.WriteFile(*output, []byte(htmlStr), 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+func cmdStats(claudeDirs []string, args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	project := fs.String("project", "", "Only consider sessions from matching projects when resolving latest")
+	format := fs.String("format", "text", "Output format: text or json")
+	pricesPath := fs.String("prices", "", "JSON file with model prices per million tokens, laid over the defaults")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "Error: session ID required")
+		fmt.Fprintln(os.Stderr, "Usage: claude-share stats <session-id|prefix|latest[~N]> [--project name] [--format text|json] [--prices file]")
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text or json)\n", *format)
+		os.Exit(1)
+	}
+	prices, err := loadPrices(*pricesPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sessionID, err := ResolveSessionID(claudeDirs, positional[0], *project)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	path, err := FindSessionPath(claudeDirs, sessionID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	tail := newSessionTail(path)
+	if _, err := tail.Poll(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing session: %v\n", err)
+		os.Exit(1)
+	}
+	report := tail.Usage(prices)
+
+	if *format == "json" {
+		out, err := json.MarshalIndent(struct {
+			SessionID string `json:"session_id"`
+			UsageReport
+		}{sessionID, report}, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Fprintf(os.Stderr, "Exported to %s\n", *output)
+		fmt.Println(string(out))
+		return
+	}
+
+	fmt.Printf("Session  %s\n", sessionID)
+	if s := tail.Summary(); s.Project != "" {
+		fmt.Printf("Project  %s\n", s.Project)
+	}
+	fmt.Println()
+	if len(report.Models) == 0 {
+		fmt.Println("No token usage recorded")
+		return
+	}
+	writeUsageTable(os.Stdout, report)
+	if !report.Total.Priced {
+		fmt.Println("\n? = no price for this model; pass --prices to add one")
+	}
+}
+
+func cmdReport(claudeDirs []string, args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	project := fs.String("project", "", "Only sessions whose project path contains this substring")
+	since := fs.String("since", "", "Only sessions started at or after this date, time or age (e.g. 2025-01-31, 7d)")
+	until := fs.String("until", "", "Only sessions started before the end of this date, or before this time or age")
+	period := fs.String("period", "day", "Group usage over time by day or week")
+	format := fs.String("format", "text", "Output format: text, json or html")
+	output := fs.String("o", "", "Output file (default: stdout)")
+	theme := fs.String("theme", "dark", "HTML color theme: dark, light or auto")
+	pricesPath := fs.String("prices", "", "JSON file with model prices per million tokens, laid over the defaults")
+	jobs := fs.Int("jobs", 4, "Number of sessions to read in parallel")
+	parseInterspersed(fs, args)
+
+	switch {
+	case *period != "day" && *period != "week":
+		fmt.Fprintf(os.Stderr, "Error: unknown period %q (want day or week)\n", *period)
+		os.Exit(1)
+	case *format != "text" && *format != "json" && *format != "html":
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text, json or html)\n", *format)
+		os.Exit(1)
+	case *jobs < 1:
+		fmt.Fprintln(os.Stderr, "Error: --jobs must be at least 1")
+		os.Exit(1)
+	}
+	requireTheme(*theme)
+	if *format != "html" && flagWasSet(fs, "theme") {
+		fmt.Fprintln(os.Stderr, "Error: --theme only applies to --format html")
+		os.Exit(1)
+	}
+
+	filter, err := parseListFilter(*project, *since, *until, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts := ReportOpts{Filter: filter, Period: *period, Jobs: *jobs}
+	if opts.Prices, err = loadPrices(*pricesPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	report, err := BuildReport(claudeDirs, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, skipped := range report.Skipped {
+		fmt.Fprintf(os.Stderr, "Skipped %s\n", skipped)
+	}
+
+	var out strings.Builder
+	switch *format {
+	case "text":
+		writeReport(&out, report)
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		out.Write(data)
+		out.WriteByte('\n')
+	case "html":
+		page, err := RenderReport(report, *theme)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		out.WriteString(page)
+	}
+
+	if *output == "" {
+		fmt.Print(out.String())
+		return
+	}
+	if err := os.WriteFile(*output, []byte(out.String()), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote report to %s\n", *output)
+}
+
+// requireTheme exits with an error unless theme is a page theme.
+func requireTheme(theme string) {
+	if !validTheme(theme) {
+		fmt.Fprintf(os.Stderr, "Error: unknown theme %q (want dark, light or auto)\n", theme)
+		os.Exit(1)
+	}
+}
+
+// flagWasSet reports whether the flag was given on the command line rather
+// than left at its default.
+func flagWasSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// imageOpts checks the --strip-images and --max-image-size flags.
+func imageOpts(strip bool, maxSize int) (ImageOpts, error) {
+	switch {
+	case maxSize < 0:
+		return ImageOpts{}, fmt.Errorf("--max-image-size must not be negative")
+	case strip && maxSize > 0:
+		return ImageOpts{}, fmt.Errorf("--strip-images and --max-image-size can't be used together")
+	}
+	return ImageOpts{Strip: strip, MaxSize: maxSize}, nil
+}
+
+// loadPrices returns the default price table, or the one in path laid over
+// it.
+func loadPrices(path string) (PriceTable, error) {
+	if path == "" {
+		return defaultPrices, nil
+	}
+	return LoadPriceTable(path)
+}
+
+// browseAddr turns a listener address into one a browser can open, so
+// ":8080" is printed as "localhost:8080".
+func browseAddr(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() || ip.IsLoopback() {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// sessionMeta builds the page header for sessionID from history.jsonl, or
+// from the session rows in tail for sessions history doesn't know, and also
+// returns the session's full project path. Details always come from tail.
+func sessionMeta(claudeDirs []string, tail *sessionTail, sessionID string, messageCount int, loc *time.Location) (SessionMeta, string, error) {
+	sessions, err := ParseHistory(claudeDirs)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return SessionMeta{SessionID: sessionID, MessageCount: messageCount, SessionDetails: tail.Details()}, "", err
+	}
+	idx := slices.IndexFunc(sessions, func(s SessionSummary) bool { return s.ID == sessionID })
+	var s SessionSummary
+	if idx >= 0 {
+		s = sessions[idx]
 	} else {
-		fmt.Print(htmlStr)
+		s = tail.Summary()
+		s.ID = sessionID
+	}
+	meta := summaryMeta(s, messageCount, loc)
+	meta.SessionDetails = tail.Details()
+	return meta, s.Project, nil
+}
+
+// summaryMeta builds the page header for s, dated in loc; nil means local.
+func summaryMeta(s SessionSummary, messageCount int, loc *time.Location) SessionMeta {
+	meta := SessionMeta{
+		SessionID:    s.ID,
+		MessageCount: messageCount,
+		FirstPrompt:  s.FirstPrompt,
+	}
+	if s.Project != "" {
+		meta.Project = filepath.Base(s.Project)
+	}
+	if s.Timestamp != 0 {
+		if loc == nil {
+			loc = time.Local
+		}
+		meta.Date = time.UnixMilli(s.Timestamp).In(loc).Format("Jan 2, 2006")
 	}
+	return meta
+}
+
+// stringList is a flag.Value that collects every occurrence of a flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// parseInterspersed parses args with fs while allowing flags to appear after
+// positional arguments, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var flagArgs, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		flagArgs = append(flagArgs, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := fs.Lookup(name); f != nil && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+	fs.Parse(flagArgs)
+	return positional
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInterspersed_FlagsAfterPositional(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	output := fs.String("o", "", "")
+	format := fs.String("format", "html", "")
+	verbose := fs.Bool("v", false, "")
+
+	positional := parseInterspersed(fs, []string{"abc", "-o", "out.md", "--format", "md", "-v", "def"})
+	assert.Equal(t, []string{"abc", "def"}, positional)
+	assert.Equal(t, "out.md", *output)
+	assert.Equal(t, "md", *format)
+	assert.True(t, *verbose)
+}
+
+func TestParseInterspersed_EqualsAndDoubleDash(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	format := fs.String("format", "html", "")
+
+	positional := parseInterspersed(fs, []string{"--format=md", "-", "--", "-not-a-flag"})
+	assert.Equal(t, []string{"-", "-not-a-flag"}, positional)
+	assert.Equal(t, "md", *format)
+}
+
+func TestSessionMeta_FallsBackToSessionRows(t *testing.T) {
+	dir := t.TempDir()
+	tail, err := readSessionTail(strings.NewReader(
+		`{"type":"user","uuid":"u1","cwd":"/home/user/app","timestamp":"2025-01-03T08:00:00Z","message":{"role":"user","content":"not in history"}}
+`))
+	require.NoError(t, err)
+
+	meta, project, err := sessionMeta([]string{dir}, tail, "d1", 4, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/home/user/app", project)
+	assert.Equal(t, "app", meta.Project)
+	assert.Equal(t, "not in history", meta.FirstPrompt)
+	assert.Equal(t, 4, meta.MessageCount)
+	assert.NotEmpty(t, meta.Date)
+}
+
+func TestSummaryMeta_DateInLocation(t *testing.T) {
+	s := SessionSummary{ID: "s1", Timestamp: time.Date(2025, 1, 31, 23, 30, 0, 0, time.UTC).UnixMilli()}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Jan 31, 2025", summaryMeta(s, 1, time.UTC).Date)
+	assert.Equal(t, "Feb 1, 2025", summaryMeta(s, 1, tokyo).Date)
+	assert.Equal(t, "Feb 1, 2025 08:30", indexDate(s.Timestamp, tokyo))
+}
+
+func TestCheckBatchFlags(t *testing.T) {
+	assert.NoError(t, checkBatchFlags("", "out", "", []string{"a", "b"}, false, 4))
+	assert.ErrorContains(t, checkBatchFlags("", "", "", []string{"a", "b"}, false, 4), "--out-dir")
+	assert.ErrorContains(t, checkBatchFlags("x.html", "out", "", nil, false, 4), "-o and --out-dir")
+	assert.ErrorContains(t, checkBatchFlags("", "out", "", []string{"-"}, false, 4), "stdin")
+	assert.ErrorContains(t, checkBatchFlags("", "out", "", nil, true, 4), "--watch")
+	assert.ErrorContains(t, checkBatchFlags("", "out", "", nil, false, 0), "--jobs")
+}
+
+func TestResolveClaudeDirs(t *testing.T) {
+	assert.Equal(t, []string{"/a", "/b"}, resolveClaudeDirs([]string{"/a", "/b"}, "/env"))
+	// CLAUDE_CONFIG_DIR is a single directory, even if it contains the list
+	// separator.
+	env := "/a" + string(filepath.ListSeparator) + "b"
+	assert.Equal(t, []string{env}, resolveClaudeDirs(nil, env))
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(home, ".claude")}, resolveClaudeDirs(nil, ""))
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func RenderMD(messages []Message, meta SessionMeta, opts RenderOpts) (string, error) {
+	var b strings.Builder
+
+	title := meta.FirstPrompt
+	if title == "" {
+		title = "Claude Conversation"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", oneLine(title))
+
+	var info []string
+	if meta.Project != "" {
+		info = append(info, "**Project:** "+meta.Project)
+	}
+	if meta.Date != "" {
+		info = append(info, "**Date:** "+meta.Date)
+	}
+	if meta.MessageCount > 0 {
+		info = append(info, fmt.Sprintf("**Messages:** %d", meta.MessageCount))
+	}
+	if d := sessionDuration(messages); d >= time.Second {
+		info = append(info, "**Duration:** "+formatDuration(d))
+	}
+	if len(meta.Models) > 0 {
+		info = append(info, "**Model:** "+strings.Join(meta.Models, ", "))
+	}
+	if meta.GitBranch != "" {
+		info = append(info, "**Branch:** `"+meta.GitBranch+"`")
+	}
+	if meta.Cwd != "" {
+		info = append(info, "**Directory:** `"+meta.Cwd+"`")
+	}
+	if meta.Version != "" {
+		info = append(info, "**Claude Code:** "+meta.Version)
+	}
+	if u := opts.Usage; u != nil {
+		info = append(info, fmt.Sprintf("**Tokens:** %s in, %s out, %s cache read, %s cache write (%s)",
+			formatTokens(u.Total.Usage.InputTokens), formatTokens(u.Total.Usage.OutputTokens),
+			formatTokens(u.Total.Usage.CacheReadTokens), formatTokens(u.Total.Usage.CacheCreationTokens), formatCost(u.Total.Cost, u.Total.Priced)))
+	}
+	if len(info) > 0 {
+		b.WriteString(strings.Join(info, " · ") + "\n\n")
+	}
+	b.WriteString("---\n\n")
+
+	writeMDMessages(&b, messages, "##")
+
+	b.WriteString("---\n\n*Shared from Claude Code*\n")
+	return b.String(), nil
+}
+
+func writeMDMessages(b *strings.Builder, messages []Message, heading string) {
+	for _, msg := range messages {
+		for n, alt := range msg.Branches {
+			fmt.Fprintf(b, "<details>\n<summary>Abandoned branch %d of %d</summary>\n\n", n+1, len(msg.Branches))
+			writeMDMessages(b, alt, heading)
+			b.WriteString("</details>\n\n")
+		}
+		if len(msg.Branches) > 0 {
+			b.WriteString("*Current branch:*\n\n")
+		}
+		writeMDMessage(b, msg, heading)
+	}
+}
+
+func writeMDMessage(b *strings.Builder, msg Message, heading string) {
+	var body strings.Builder
+	for _, block := range msg.Blocks {
+		switch block.Type {
+		case "text":
+			body.WriteString(strings.TrimSpace(block.Text) + "\n\n")
+		case "thinking":
+			body.WriteString("<details>\n<summary>Thinking</summary>\n\n")
+			body.WriteString(strings.TrimSpace(block.Text) + "\n\n")
+			body.WriteString("</details>\n\n")
+		case "image":
+			body.WriteString("*[Image]*\n\n")
+		case "tool_use":
+			writeMDTool(&body, block, heading)
+		case "tool_result":
+			if msg.Role != "assistant" && block.ToolName == "" {
+				continue
+			}
+			label := "Result"
+			if block.IsError {
+				label = "Error"
+			}
+			if block.ToolName != "" {
+				label = block.ToolName + " " + label
+			}
+			fmt.Fprintf(&body, "<details>\n<summary>%s</summary>\n\n%s</details>\n\n", label, mdFence(truncate(block.Text, 2000), ""))
+		}
+	}
+	if body.Len() == 0 {
+		return
+	}
+
+	sender := "You"
+	if msg.Role == "assistant" {
+		sender = "Claude"
+	}
+	fmt.Fprintf(b, "%s %s\n\n%s", heading, sender, body.String())
+}
+
+func writeMDTool(b *strings.Builder, block ContentBlock, heading string) {
+	summary := block.ToolName
+	if s := toolSummary(block); s != "" {
+		summary += ": " + s
+	}
+	if block.Result != nil && block.Result.IsError {
+		summary += " (error)"
+	}
+	fmt.Fprintf(b, "<details>\n<summary>🔧 %s</summary>\n\n", html.EscapeString(oneLine(summary)))
+
+	var input map[string]any
+	json.Unmarshal([]byte(block.ToolInput), &input)
+	errorsOnly := true
+	switch block.ToolName {
+	case "Edit":
+		path := inputString(input, "file_path")
+		b.WriteString(mdFence(unifiedDiff(path, inputString(input, "old_string"), inputString(input, "new_string")), "diff"))
+	case "MultiEdit":
+		path := inputString(input, "file_path")
+		edits, _ := input["edits"].([]any)
+		for _, e := range edits {
+			edit, _ := e.(map[string]any)
+			b.WriteString(mdFence(unifiedDiff(path, inputString(edit, "old_string"), inputString(edit, "new_string")), "diff"))
+		}
+	case "Write":
+		b.WriteString(mdFence(inputString(input, "content"), mdLang(inputString(input, "file_path"))))
+	case "Read":
+		fmt.Fprintf(b, "`%s`\n\n", inputString(input, "file_path"))
+		errorsOnly = false
+	case "Bash":
+		b.WriteString(mdFence(inputString(input, "command"), "bash"))
+		errorsOnly = false
+	case "TodoWrite":
+		todos, _ := input["todos"].([]any)
+		for _, t := range todos {
+			todo, _ := t.(map[string]any)
+			mark := " "
+			if inputString(todo, "status") == "completed" {
+				mark = "x"
+			}
+			fmt.Fprintf(b, "- [%s] %s\n", mark, inputString(todo, "content"))
+		}
+		b.WriteString("\n")
+	default:
+		var pretty bytes.Buffer
+		if err := jsonIndent(&pretty, []byte(block.ToolInput)); err == nil {
+			b.WriteString(mdFence(pretty.String(), "json"))
+		} else {
+			b.WriteString(mdFence(block.ToolInput, "json"))
+		}
+		errorsOnly = false
+	}
+
+	if r := block.Result; r != nil && r.Text != "" && (r.IsError || !errorsOnly) {
+		label := "Output"
+		if block.Result.IsError {
+			label = "Error"
+		}
+		fmt.Fprintf(b, "**%s**\n\n%s", label, mdFence(truncate(block.Result.Text, 2000), ""))
+	}
+
+	if len(block.Sidechain) > 0 {
+		fmt.Fprintf(b, "<details>\n<summary>Subagent conversation (%d messages)</summary>\n\n", len(block.Sidechain))
+		writeMDMessages(b, block.Sidechain, heading+"#")
+		b.WriteString("</details>\n\n")
+	}
+	b.WriteString("</details>\n\n")
+}
+
+// mdFence wraps code in a fenced block whose fence is longer than any run of
+// backticks inside the code.
+func mdFence(code, lang string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + strings.TrimRight(code, "\n") + "\n" + fence + "\n\n"
+}
+
+func mdLang(path string) string {
+	return strings.TrimPrefix(filepath.Ext(path), ".")
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMD_BasicConversation(t *testing.T) {
+	meta := SessionMeta{SessionID: "t", Project: "myproject", Date: "Jan 1, 2025", MessageCount: 2, FirstPrompt: "Fix\nthe bug"}
+
+	md, err := RenderMD([]Message{userMsg("Hello"), assistantMsg("Hi **there**")}, meta, RenderOpts{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(md, "# Fix the bug\n"))
+	assert.Contains(t, md, "**Project:** myproject · **Date:** Jan 1, 2025 · **Messages:** 2")
+	assert.Contains(t, md, "## You\n\nHello\n")
+	assert.Contains(t, md, "## Claude\n\nHi **there**\n")
+}
+
+func TestRenderMD_Duration(t *testing.T) {
+	first, last := userMsg("Hello"), assistantMsg("Hi")
+	first.Timestamp, last.Timestamp = "2025-01-01T10:00:00Z", "2025-01-01T10:04:12Z"
+
+	md, err := RenderMD([]Message{first, last}, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.Contains(t, md, "**Duration:** 4m 12s")
+}
+
+func TestRenderMD_FallbackTitle(t *testing.T) {
+	md, err := RenderMD([]Message{userMsg("hi")}, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(md, "# Claude Conversation\n"))
+}
+
+func TestRenderMD_ThinkingInDetails(t *testing.T) {
+	messages := []Message{{Role: "assistant", Blocks: []ContentBlock{{Type: "thinking", Text: "Pondering"}, textBlock("Answer")}}}
+
+	md, err := RenderMD(messages, stubMeta, RenderOpts{IncludeThinking: true})
+	require.NoError(t, err)
+	assert.Contains(t, md, "<details>\n<summary>Thinking</summary>\n\nPondering\n\n</details>")
+}
+
+func TestRenderMD_ToolCallWithResult(t *testing.T) {
+	messages := []Message{{Role: "assistant", Blocks: []ContentBlock{
+		{Type: "tool_use", ToolName: "Bash", ToolInput: `{"command":"ls","description":"List files"}`, Result: &ContentBlock{Text: "main.go"}},
+		{Type: "tool_use", ToolName: "WebFetch", ToolInput: `{"url":"https://example.com"}`},
+	}}}
+
+	md, err := RenderMD(messages, stubMeta, RenderOpts{IncludeTools: true})
+	require.NoError(t, err)
+	assert.Contains(t, md, "<summary>🔧 Bash: List files</summary>")
+	assert.Contains(t, md, "```bash\nls\n```")
+	assert.Contains(t, md, "**Output**\n\n```\nmain.go\n```")
+	assert.Contains(t, md, "<summary>🔧 WebFetch</summary>")
+	assert.Contains(t, md, "```json\n{\n  \"url\": \"https://example.com\"\n}\n```")
+}
+
+func TestRenderMD_EditAsDiff(t *testing.T) {
+	messages := []Message{{Role: "assistant", Blocks: []ContentBlock{
+		{Type: "tool_use", ToolName: "Edit", ToolInput: `{"file_path":"a.go","old_string":"x := 1","new_string":"x := 2"}`, Result: &ContentBlock{Text: "updated"}},
+	}}}
+
+	md, err := RenderMD(messages, stubMeta, RenderOpts{IncludeTools: true})
+	require.NoError(t, err)
+	assert.Contains(t, md, "```diff\n")
+	assert.Contains(t, md, "\n-x := 1\n+x := 2\n")
+	assert.NotContains(t, md, "updated")
+}
+
+func TestRenderMD_SkipsUserToolResultMessages(t *testing.T) {
+	messages := []Message{{Role: "user", Blocks: []ContentBlock{{Type: "tool_result", Text: "orphan"}}}}
+
+	md, err := RenderMD(messages, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.NotContains(t, md, "## You")
+	assert.NotContains(t, md, "orphan")
+}
+
+func TestRenderMD_ShowsBranchResultForSharedCall(t *testing.T) {
+	messages := []Message{{Role: "user", Blocks: []ContentBlock{{Type: "tool_result", ToolName: "Bash", Text: "first run"}}}}
+
+	md, err := RenderMD(messages, stubMeta, RenderOpts{IncludeTools: true})
+	require.NoError(t, err)
+	assert.Contains(t, md, "## You\n\n<details>\n<summary>Bash Result</summary>")
+	assert.Contains(t, md, "first run")
+}
+
+func TestRenderMD_BranchesAndSidechains(t *testing.T) {
+	edited := userMsg("Edited")
+	edited.Branches = [][]Message{{userMsg("Original")}}
+	messages := []Message{
+		{Role: "assistant", Blocks: []ContentBlock{
+			{Type: "tool_use", ToolName: "Task", ToolInput: `{}`, Sidechain: []Message{assistantMsg("Sub answer")}},
+		}},
+		edited,
+	}
+
+	md, err := RenderMD(messages, stubMeta, RenderOpts{IncludeTools: true})
+	require.NoError(t, err)
+	assert.Contains(t, md, "<summary>Subagent conversation (1 messages)</summary>\n\n### Claude\n\nSub answer")
+	assert.Contains(t, md, "<summary>Abandoned branch 1 of 1</summary>\n\n## You\n\nOriginal")
+	assert.Contains(t, md, "*Current branch:*\n\n## You\n\nEdited")
+}
+
+func TestMDFence_LongerThanContentBackticks(t *testing.T) {
+	assert.Equal(t, "```go\nx\n```\n\n", mdFence("x\n", "go"))
+	assert.Equal(t, "````\na ``` b\n````\n\n", mdFence("a ``` b", ""))
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"sort"
@@ -20,16 +22,24 @@ type SessionSummary struct {
 type Message struct {
 	Role      string // "user" or "assistant"
 	Blocks    []ContentBlock
-	Timestamp string // ISO 8601
+	Timestamp string      // ISO 8601
+	Branches  [][]Message // abandoned alternatives to this message and everything after it
+	Model     string      // assistant messages only
+	Usage     *Usage      // token usage of an assistant message, if recorded
 }
 
 type ContentBlock struct {
-	Type      string // "text", "thinking", "tool_use", "tool_result"
+	Type      string // "text", "thinking", "tool_use", "tool_result", "image"
 	Text      string
 	ToolName  string
 	ToolInput string // JSON
 	ToolUseID string
 	IsError   bool
+	Result    *ContentBlock  // tool_result paired with this tool_use, if any
+	Sidechain []Message      // subagent conversation spawned by a Task tool_use
+	MediaType string         // image blocks, e.g. "image/png"
+	Data      string         // image blocks: base64 data, empty if stripped
+	Images    []ContentBlock // images returned in a tool_result
 }
 
 type ParseOpts struct {
@@ -45,14 +55,36 @@ type historyEntry struct {
 }
 
 type sessionRow struct {
-	Type      string          `json:"type"`
-	UUID      string          `json:"uuid"`
-	Timestamp string          `json:"timestamp"`
-	SessionID string          `json:"sessionId"`
-	IsMeta    bool            `json:"isMeta"`
-	Message   json.RawMessage `json:"message"`
-	Content   json.RawMessage `json:"content"`
-	Subtype   string          `json:"subtype"`
+	Type        string          `json:"type"`
+	UUID        string          `json:"uuid"`
+	ParentUUID  rowRef          `json:"parentUuid"`
+	LogicalUUID string          `json:"logicalParentUuid"`
+	Timestamp   string          `json:"timestamp"`
+	SessionID   string          `json:"sessionId"`
+	IsMeta      bool            `json:"isMeta"`
+	IsSidechain bool            `json:"isSidechain"`
+	Message     json.RawMessage `json:"message"`
+	Content     json.RawMessage `json:"content"`
+	Subtype     string          `json:"subtype"`
+	Cwd         string          `json:"cwd"`
+	GitBranch   string          `json:"gitBranch"`
+	Version     string          `json:"version"`
+}
+
+// rowRef is a reference to another row's uuid. Null is set for an explicit
+// JSON null, which Claude Code writes for the root of a conversation, as
+// opposed to a missing field.
+type rowRef struct {
+	UUID string
+	Null bool
+}
+
+func (r *rowRef) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*r = rowRef{Null: true}
+		return nil
+	}
+	return json.Unmarshal(data, &r.UUID)
 }
 
 type apiMessage struct {
@@ -60,6 +92,15 @@ type apiMessage struct {
 	Role       string          `json:"role"`
 	Content    json.RawMessage `json:"content"`
 	StopReason *string         `json:"stop_reason"`
+	Model      string          `json:"model"`
+	Usage      *apiUsage       `json:"usage"`
+}
+
+type apiUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
 }
 
 type contentBlockRaw struct {
@@ -72,18 +113,62 @@ type contentBlockRaw struct {
 	ToolUseID string          `json:"tool_use_id"`
 	Content   json.RawMessage `json:"content"`
 	IsError   bool            `json:"is_error"`
+	Source    *imageSource    `json:"source"`
 }
 
-func ParseHistory(claudeDir string) ([]SessionSummary, error) {
-	path := filepath.Join(claudeDir, "history.jsonl")
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("open history: %w", err)
+type imageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// image converts an image block with inline base64 data. Images referenced
+// by URL or file ID are skipped.
+func (b contentBlockRaw) image() (ContentBlock, bool) {
+	src := b.Source
+	if src == nil || src.Type != "base64" || src.Data == "" || !strings.HasPrefix(src.MediaType, "image/") {
+		return ContentBlock{}, false
 	}
-	defer f.Close()
+	return ContentBlock{Type: "image", MediaType: src.MediaType, Data: src.Data}, true
+}
 
+func ParseHistory(claudeDirs []string) ([]SessionSummary, error) {
 	seen := make(map[string]*SessionSummary)
 	var order []string
+	var missing error
+	for _, dir := range claudeDirs {
+		err := parseHistoryFile(filepath.Join(dir, "history.jsonl"), seen, &order)
+		if errors.Is(err, os.ErrNotExist) {
+			missing = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		missing = nil
+	}
+	if missing != nil && len(order) == 0 {
+		return nil, missing
+	}
+
+	results := make([]SessionSummary, 0, len(order))
+	for _, id := range order {
+		results = append(results, *seen[id])
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp > results[j].Timestamp
+	})
+	return results, nil
+}
+
+// parseHistoryFile adds the sessions in one history.jsonl to seen, keeping
+// the first entry for each session.
+func parseHistoryFile(path string, seen map[string]*SessionSummary, order *[]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	defer f.Close()
 
 	scanner := bufio.NewScanner(f)
 	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
@@ -102,49 +187,77 @@ func ParseHistory(claudeDir string) ([]SessionSummary, error) {
 				FirstPrompt: e.Display,
 				Timestamp:   e.Timestamp,
 			}
-			order = append(order, e.SessionID)
+			*order = append(*order, e.SessionID)
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scan history: %w", err)
-	}
-
-	results := make([]SessionSummary, 0, len(order))
-	for _, id := range order {
-		results = append(results, *seen[id])
+		return fmt.Errorf("scan history: %w", err)
 	}
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Timestamp > results[j].Timestamp
-	})
-	return results, nil
+	return nil
 }
 
-func FindSessionPath(claudeDir, sessionID string) (string, error) {
-	projectsDir := filepath.Join(claudeDir, "projects")
-	entries, err := os.ReadDir(projectsDir)
-	if err != nil {
-		return "", fmt.Errorf("read projects dir: %w", err)
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
+func FindSessionPath(claudeDirs []string, sessionID string) (string, error) {
+	var readErr error
+	found := false
+	for _, dir := range claudeDirs {
+		projectsDir := filepath.Join(dir, "projects")
+		entries, err := os.ReadDir(projectsDir)
+		if err != nil {
+			readErr = err
 			continue
 		}
-		jsonlPath := filepath.Join(projectsDir, entry.Name(), sessionID+".jsonl")
-		if info, err := os.Stat(jsonlPath); err == nil && !info.IsDir() {
-			return jsonlPath, nil
+		found = true
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			jsonlPath := filepath.Join(projectsDir, entry.Name(), sessionID+".jsonl")
+			if info, err := os.Stat(jsonlPath); err == nil && !info.IsDir() {
+				return jsonlPath, nil
+			}
 		}
 	}
+	if !found && readErr != nil {
+		return "", fmt.Errorf("read projects dir: %w", readErr)
+	}
 	return "", fmt.Errorf("session %s not found", sessionID)
 }
 
+// subagentTools are the tool names Claude Code uses to spawn a subagent.
+var subagentTools = map[string]bool{"Task": true, "Agent": true}
+
 func ParseSession(path string, opts ParseOpts) ([]Message, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("open session: %w", err)
+	t := newSessionTail(path)
+	if _, err := t.Poll(); err != nil {
+		return nil, err
 	}
-	defer f.Close()
+	return t.Messages(opts), nil
+}
+
+// buildSession turns the rows of a session file, including subagent rows,
+// into the conversation along the active branch.
+func buildSession(rows []sessionRow, opts ParseOpts) []Message {
+	var mainRows, sideRows []sessionRow
+	for _, row := range rows {
+		if row.IsSidechain {
+			sideRows = append(sideRows, row)
+		} else {
+			mainRows = append(mainRows, row)
+		}
+	}
+
+	msgs := buildConversation(mainRows, opts)
+	if opts.IncludeTools && len(sideRows) > 0 {
+		attachSidechains(msgs, groupSidechains(sideRows), opts)
+	}
+	return pairToolResults(msgs, nil)
+}
 
+// buildMessages turns rows into messages in row order, merging streamed
+// assistant rows that share a message ID. starts[i] is the index of the row
+// that opened msgs[i].
+func buildMessages(rows []sessionRow, opts ParseOpts) (msgs []Message, starts []int) {
 	type userEntry struct {
 		msg Message
 		seq int
@@ -153,29 +266,17 @@ func ParseSession(path string, opts ParseOpts) ([]Message, error) {
 		blocks   []ContentBlock
 		ts       string
 		firstSeq int
+		model    string
+		usage    *Usage
 	}
 
 	var userMsgs []userEntry
 	assistantGroups := make(map[string]*assistantGroup)
 	var assistantIDs []string
 
-	seq := 0
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
-
-	for scanner.Scan() {
-		var row sessionRow
-		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
-			seq++
-			continue
-		}
-
+	for seq, row := range rows {
 		switch row.Type {
 		case "user":
-			if row.IsMeta {
-				seq++
-				continue
-			}
 			msg := parseUserRow(row, opts)
 			if msg != nil {
 				userMsgs = append(userMsgs, userEntry{msg: *msg, seq: seq})
@@ -183,17 +284,14 @@ func ParseSession(path string, opts ParseOpts) ([]Message, error) {
 
 		case "assistant":
 			if row.Message == nil {
-				seq++
 				continue
 			}
 			var api apiMessage
 			if err := json.Unmarshal(row.Message, &api); err != nil {
-				seq++
 				continue
 			}
 			blocks := extractAssistantBlocks(api.Content, opts)
 			if len(blocks) == 0 {
-				seq++
 				continue
 			}
 			grp, exists := assistantGroups[api.ID]
@@ -203,11 +301,14 @@ func ParseSession(path string, opts ParseOpts) ([]Message, error) {
 				assistantIDs = append(assistantIDs, api.ID)
 			}
 			grp.blocks = append(grp.blocks, blocks...)
+			grp.model = api.Model
+			if api.Usage != nil {
+				// Each streamed row repeats the message's usage; the last
+				// one has the final output token count.
+				u := api.Usage.usage()
+				grp.usage = &u
+			}
 		}
-		seq++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
 
 	type seqMsg struct {
@@ -222,18 +323,194 @@ func ParseSession(path string, opts ParseOpts) ([]Message, error) {
 		grp := assistantGroups[id]
 		if len(grp.blocks) > 0 {
 			all = append(all, seqMsg{
-				msg: Message{Role: "assistant", Blocks: grp.blocks, Timestamp: grp.ts},
+				msg: Message{Role: "assistant", Blocks: grp.blocks, Timestamp: grp.ts, Model: grp.model, Usage: grp.usage},
 				seq: grp.firstSeq,
 			})
 		}
 	}
 	sort.Slice(all, func(i, j int) bool { return all[i].seq < all[j].seq })
 
-	msgs := make([]Message, len(all))
+	msgs = make([]Message, len(all))
+	starts = make([]int, len(all))
 	for i, a := range all {
 		msgs[i] = a.msg
+		starts[i] = a.seq
+	}
+	return msgs, starts
+}
+
+// groupSidechains splits sidechain rows into one thread per subagent run by
+// following parentUuid links back to each thread's root row.
+func groupSidechains(rows []sessionRow) [][]sessionRow {
+	parents := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if row.UUID != "" {
+			parents[row.UUID] = row.ParentUUID.UUID
+		}
+	}
+	rootOf := func(id string) string {
+		for i := 0; i < len(parents); i++ {
+			parent, ok := parents[id]
+			if !ok || parent == "" {
+				break
+			}
+			if _, known := parents[parent]; !known {
+				break
+			}
+			id = parent
+		}
+		return id
+	}
+
+	threads := make(map[string][]sessionRow)
+	var order []string
+	for _, row := range rows {
+		root := rootOf(row.UUID)
+		if _, ok := threads[root]; !ok {
+			order = append(order, root)
+		}
+		threads[root] = append(threads[root], row)
+	}
+
+	result := make([][]sessionRow, 0, len(order))
+	for _, root := range order {
+		result = append(result, threads[root])
+	}
+	return result
+}
+
+// attachSidechains hangs each subagent thread off the tool_use block that
+// spawned it. Threads are matched by the prompt passed to the tool, falling
+// back to the order in which the tool calls were made.
+func attachSidechains(msgs []Message, threads [][]sessionRow, opts ParseOpts) {
+	var calls []*ContentBlock
+	walkBlocks(msgs, func(b *ContentBlock) {
+		if b.Type == "tool_use" && subagentTools[b.ToolName] {
+			calls = append(calls, b)
+		}
+	})
+
+	var unmatched [][]Message
+	for _, thread := range threads {
+		sub, _ := buildMessages(thread, opts)
+		sub = pairToolResults(sub, nil)
+		if len(sub) == 0 {
+			continue
+		}
+		matched := false
+		if prompt := firstUserText(sub); prompt != "" {
+			for _, call := range calls {
+				if call.Sidechain == nil && toolInputString(call.ToolInput, "prompt") == prompt {
+					call.Sidechain = sub
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, sub)
+		}
+	}
+
+	for _, call := range calls {
+		if len(unmatched) == 0 {
+			break
+		}
+		if call.Sidechain == nil {
+			call.Sidechain = unmatched[0]
+			unmatched = unmatched[1:]
+		}
+	}
+}
+
+// pairToolResults moves each tool_result onto the tool_use block with the same
+// ToolUseID and drops user messages left empty by the move. Results without a
+// matching call stay where they are. shared holds the calls made before msgs
+// branched off; a result for one of those stays in place too, since the call
+// belongs to every branch, but takes the call's ToolName so it can be shown.
+func pairToolResults(msgs []Message, shared map[string]*ContentBlock) []Message {
+	pending := make(map[string]*ContentBlock)
+
+	result := msgs[:0]
+	for _, msg := range msgs {
+		if len(msg.Branches) > 0 {
+			prefix := maps.Clone(shared)
+			if prefix == nil {
+				prefix = make(map[string]*ContentBlock, len(pending))
+			}
+			maps.Copy(prefix, pending)
+			for i, alt := range msg.Branches {
+				msg.Branches[i] = pairToolResults(alt, prefix)
+			}
+		}
+
+		kept := msg.Blocks[:0]
+		for j := range msg.Blocks {
+			b := msg.Blocks[j]
+			if b.Type == "tool_result" {
+				if call, ok := pending[b.ToolUseID]; ok && call.Result == nil {
+					call.Result = &b
+					continue
+				}
+				if call, ok := shared[b.ToolUseID]; ok {
+					b.ToolName = call.ToolName
+				}
+			}
+			kept = append(kept, b)
+		}
+		msg.Blocks = kept
+
+		for j := range msg.Blocks {
+			if b := &msg.Blocks[j]; b.Type == "tool_use" && b.ToolUseID != "" {
+				pending[b.ToolUseID] = b
+			}
+		}
+		if len(msg.Blocks) > 0 || len(msg.Branches) > 0 {
+			result = append(result, msg)
+		}
 	}
-	return msgs, nil
+	return result
+}
+
+// walkBlocks calls fn for every content block in msgs, including paired tool
+// results and blocks in abandoned branches and subagent conversations.
+func walkBlocks(msgs []Message, fn func(*ContentBlock)) {
+	for i := range msgs {
+		for j := range msgs[i].Blocks {
+			b := &msgs[i].Blocks[j]
+			fn(b)
+			if b.Result != nil {
+				fn(b.Result)
+			}
+			walkBlocks(b.Sidechain, fn)
+		}
+		for _, branch := range msgs[i].Branches {
+			walkBlocks(branch, fn)
+		}
+	}
+}
+
+func firstUserText(msgs []Message) string {
+	for _, m := range msgs {
+		if m.Role != "user" {
+			continue
+		}
+		for _, b := range m.Blocks {
+			if b.Type == "text" {
+				return strings.TrimSpace(b.Text)
+			}
+		}
+	}
+	return ""
+}
+
+func toolInputString(input, key string) string {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(input), &fields); err != nil {
+		return ""
+	}
+	s, _ := fields[key].(string)
+	return strings.TrimSpace(s)
 }
 
 func parseUserRow(row sessionRow, opts ParseOpts) *Message {
@@ -274,12 +551,17 @@ func parseUserRow(row sessionRow, opts ParseOpts) *Message {
 				Text:      text,
 				ToolUseID: b.ToolUseID,
 				IsError:   b.IsError,
+				Images:    toolResultImages(b.Content),
 			})
 		case "text":
 			if strings.Contains(b.Text, "<command-name>") || strings.Contains(b.Text, "<local-command") || strings.Contains(b.Text, "<system-reminder>") {
 				continue
 			}
 			msg.Blocks = append(msg.Blocks, ContentBlock{Type: "text", Text: b.Text})
+		case "image":
+			if img, ok := b.image(); ok {
+				msg.Blocks = append(msg.Blocks, img)
+			}
 		}
 	}
 
@@ -355,3 +637,22 @@ func extractToolResultContent(raw json.RawMessage) string {
 	}
 	return string(raw)
 }
+
+// toolResultImages returns the images in a tool_result's content, such as a
+// screenshot or an image file read by the Read tool.
+func toolResultImages(raw json.RawMessage) []ContentBlock {
+	var arr []contentBlockRaw
+	if err := json.Unmarshal(raw, &arr); err != nil {
+		return nil
+	}
+	var images []ContentBlock
+	for _, a := range arr {
+		if a.Type != "image" {
+			continue
+		}
+		if img, ok := a.image(); ok {
+			images = append(images, img)
+		}
+	}
+	return images
+}
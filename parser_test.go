@@ -30,7 +30,7 @@ func TestParseHistory_Basic(t *testing.T) {
 {"display":"second prompt","timestamp":2000,"project":"/home/user/proj","sessionId":"bbb"}
 `)
 
-	sessions, err := ParseHistory(dir)
+	sessions, err := ParseHistory([]string{dir})
 	require.NoError(t, err)
 	assert.Len(t, sessions, 2)
 	assert.Equal(t, "bbb", sessions[0].ID)
@@ -47,7 +47,7 @@ func TestParseHistory_DeduplicatesBySessionID(t *testing.T) {
 {"display":"third","timestamp":3000,"project":"/proj","sessionId":"aaa"}
 `)
 
-	sessions, err := ParseHistory(dir)
+	sessions, err := ParseHistory([]string{dir})
 	require.NoError(t, err)
 	assert.Len(t, sessions, 1)
 	assert.Equal(t, "first", sessions[0].FirstPrompt)
@@ -60,7 +60,7 @@ func TestParseHistory_SkipsEmptySessionID(t *testing.T) {
 {"display":"has id","timestamp":2000,"project":"/proj","sessionId":"bbb"}
 `)
 
-	sessions, err := ParseHistory(dir)
+	sessions, err := ParseHistory([]string{dir})
 	require.NoError(t, err)
 	assert.Len(t, sessions, 1)
 	assert.Equal(t, "bbb", sessions[0].ID)
@@ -74,24 +74,41 @@ func TestParseHistory_SkipsMalformedLines(t *testing.T) {
 {broken json
 `)
 
-	sessions, err := ParseHistory(dir)
+	sessions, err := ParseHistory([]string{dir})
 	require.NoError(t, err)
 	assert.Len(t, sessions, 1)
 	assert.Equal(t, "aaa", sessions[0].ID)
 }
 
 func TestParseHistory_MissingFile(t *testing.T) {
-	_, err := ParseHistory(t.TempDir())
+	_, err := ParseHistory([]string{t.TempDir()})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "open history")
 }
 
+func TestParseHistory_MergesMultipleDirs(t *testing.T) {
+	primary, backup, empty := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTempFile(t, primary, "history.jsonl",
+		`{"display":"primary copy","timestamp":2000,"project":"/proj","sessionId":"aaa"}
+`)
+	writeTempFile(t, backup, "history.jsonl",
+		`{"display":"backup copy","timestamp":2000,"project":"/proj","sessionId":"aaa"}
+{"display":"only in backup","timestamp":1000,"project":"/old","sessionId":"bbb"}
+`)
+
+	sessions, err := ParseHistory([]string{primary, empty, backup})
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "primary copy", sessions[0].FirstPrompt)
+	assert.Equal(t, "only in backup", sessions[1].FirstPrompt)
+}
+
 func TestFindSessionPath_FindsJSONL(t *testing.T) {
 	dir := t.TempDir()
 	projDir := filepath.Join(dir, "projects", "my-project")
 	writeTempFile(t, projDir, "sess-123.jsonl", `{}`)
 
-	path, err := FindSessionPath(dir, "sess-123")
+	path, err := FindSessionPath([]string{dir}, "sess-123")
 	require.NoError(t, err)
 	assert.Equal(t, filepath.Join(projDir, "sess-123.jsonl"), path)
 }
@@ -100,13 +117,29 @@ func TestFindSessionPath_NotFound(t *testing.T) {
 	dir := t.TempDir()
 	require.NoError(t, os.MkdirAll(filepath.Join(dir, "projects", "my-project"), 0755))
 
-	_, err := FindSessionPath(dir, "nonexistent")
+	_, err := FindSessionPath([]string{dir}, "nonexistent")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
 }
 
+func TestFindSessionPath_SearchesDirsInOrder(t *testing.T) {
+	primary, backup := t.TempDir(), t.TempDir()
+	writeTempFile(t, filepath.Join(backup, "projects", "p"), "old.jsonl", `{}`)
+	writeTempFile(t, filepath.Join(backup, "projects", "p"), "both.jsonl", `{}`)
+	writeTempFile(t, filepath.Join(primary, "projects", "p"), "both.jsonl", `{}`)
+	dirs := []string{primary, backup}
+
+	path, err := FindSessionPath(dirs, "old")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(backup, "projects", "p", "old.jsonl"), path)
+
+	path, err = FindSessionPath(dirs, "both")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(primary, "projects", "p", "both.jsonl"), path)
+}
+
 func TestFindSessionPath_NoProjectsDir(t *testing.T) {
-	_, err := FindSessionPath(t.TempDir(), "anything")
+	_, err := FindSessionPath([]string{t.TempDir()}, "anything")
 	assert.Error(t, err)
 }
 
@@ -139,6 +172,19 @@ func TestParseSession_AssistantGroupsByMessageID(t *testing.T) {
 	assert.Equal(t, " world", msgs[0].Blocks[1].Text)
 }
 
+func TestParseSession_AssistantModelAndUsage(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"assistant","message":{"id":"msg1","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"text","text":"Hello"}],"usage":{"input_tokens":10,"output_tokens":1,"cache_read_input_tokens":500}}}
+{"type":"assistant","message":{"id":"msg1","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"text","text":" world"}],"usage":{"input_tokens":10,"output_tokens":42,"cache_read_input_tokens":500,"cache_creation_input_tokens":7}}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "claude-sonnet-4-5", msgs[0].Model)
+	assert.Equal(t, &Usage{InputTokens: 10, OutputTokens: 42, CacheReadTokens: 500, CacheCreationTokens: 7}, msgs[0].Usage)
+}
+
 func TestParseSession_InterleaveOrder(t *testing.T) {
 	path := writeSession(t,
 		`{"type":"user","timestamp":"T1","message":{"id":"u1","role":"user","content":"Question 1"}}
@@ -271,6 +317,87 @@ func TestParseSession_ToolResultError(t *testing.T) {
 	assert.True(t, msgs[0].Blocks[0].IsError)
 }
 
+func TestParseSession_PairsToolResultWithCall(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"assistant","timestamp":"T1","message":{"id":"a1","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"false"}}]}}
+{"type":"user","timestamp":"T2","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"exit 1","is_error":true}]}}
+{"type":"assistant","timestamp":"T3","message":{"id":"a2","role":"assistant","content":[{"type":"text","text":"It failed"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{IncludeTools: true})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	call := msgs[0].Blocks[0]
+	require.NotNil(t, call.Result)
+	assert.Equal(t, "exit 1", call.Result.Text)
+	assert.True(t, call.Result.IsError)
+	assert.Equal(t, "It failed", msgs[1].Blocks[0].Text)
+}
+
+func TestParseSession_UserImages(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","message":{"role":"user","content":[{"type":"text","text":"What is wrong here?"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgo="}},{"type":"image","source":{"type":"url","url":"https://example.com/x.png"}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/jpeg","data":"/9j/4AAQ"}}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, []ContentBlock{
+		{Type: "text", Text: "What is wrong here?"},
+		{Type: "image", MediaType: "image/png", Data: "iVBORw0KGgo="},
+	}, msgs[0].Blocks)
+	assert.Equal(t, []ContentBlock{{Type: "image", MediaType: "image/jpeg", Data: "/9j/4AAQ"}}, msgs[1].Blocks)
+}
+
+func TestParseSession_ToolResultImages(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"assistant","message":{"id":"a1","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"/tmp/shot.png"}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgo="}}]}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{IncludeTools: true})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	result := msgs[0].Blocks[0].Result
+	require.NotNil(t, result)
+	assert.Empty(t, result.Text)
+	assert.Equal(t, []ContentBlock{{Type: "image", MediaType: "image/png", Data: "iVBORw0KGgo="}}, result.Images)
+}
+
+func TestParseSession_PairingKeepsUserText(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"assistant","timestamp":"T1","message":{"id":"a1","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{}}]}}
+{"type":"user","timestamp":"T2","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"data"},{"type":"text","text":"also look at this"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{IncludeTools: true})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "data", msgs[0].Blocks[0].Result.Text)
+	require.Len(t, msgs[1].Blocks, 1)
+	assert.Equal(t, "also look at this", msgs[1].Blocks[0].Text)
+}
+
+func TestParseSession_PairsResultsInsideBranches(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"assistant","uuid":"a1","timestamp":"T1","message":{"id":"a1","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Bash","input":{}}]}}
+{"type":"user","uuid":"r1","parentUuid":"a1","timestamp":"T2","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"first run"}]}}
+{"type":"assistant","uuid":"a2","parentUuid":"r1","timestamp":"T3","message":{"id":"a2","role":"assistant","content":[{"type":"text","text":"abandoned"}]}}
+{"type":"user","uuid":"r2","parentUuid":"a1","timestamp":"T4","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"second run"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{IncludeTools: true})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "second run", msgs[0].Blocks[0].Result.Text)
+	require.Len(t, msgs[1].Branches, 1)
+	abandoned := msgs[1].Branches[0]
+	assert.Equal(t, "first run", abandoned[0].Blocks[0].Text)
+	assert.Equal(t, "Bash", abandoned[0].Blocks[0].ToolName)
+	assert.Equal(t, "abandoned", abandoned[1].Blocks[0].Text)
+}
+
 func TestParseSession_EmptyAssistantBlocksSkipped(t *testing.T) {
 	path := writeSession(t,
 		`{"type":"assistant","timestamp":"T1","message":{"id":"a1","role":"assistant","content":[{"type":"text","text":""}],"stop_reason":"end_turn"}}
@@ -286,6 +413,170 @@ func TestParseSession_MissingFile(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestParseSession_SidechainAttachedToTaskByPrompt(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","uuid":"u1","timestamp":"T1","message":{"role":"user","content":"Investigate"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","timestamp":"T2","message":{"id":"a1","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Task","input":{"prompt":"Find the bug"}}]}}
+{"type":"user","uuid":"s1","isSidechain":true,"timestamp":"T3","message":{"role":"user","content":"Find the bug"}}
+{"type":"assistant","uuid":"s2","parentUuid":"s1","isSidechain":true,"timestamp":"T4","message":{"id":"s2","role":"assistant","content":[{"type":"text","text":"Found it in main.go"}]}}
+{"type":"assistant","uuid":"a2","parentUuid":"a1","timestamp":"T5","message":{"id":"a2","role":"assistant","content":[{"type":"text","text":"The subagent found it"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{IncludeTools: true})
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	call := msgs[1].Blocks[0]
+	assert.Equal(t, "Task", call.ToolName)
+	require.Len(t, call.Sidechain, 2)
+	assert.Equal(t, "Find the bug", call.Sidechain[0].Blocks[0].Text)
+	assert.Equal(t, "Found it in main.go", call.Sidechain[1].Blocks[0].Text)
+	assert.Equal(t, "The subagent found it", msgs[2].Blocks[0].Text)
+}
+
+func TestParseSession_SidechainsFallBackToCallOrder(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"assistant","uuid":"a1","timestamp":"T1","message":{"id":"a1","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Task","input":{"prompt":"first"}},{"type":"tool_use","id":"toolu_2","name":"Task","input":{"prompt":"second"}}]}}
+{"type":"user","uuid":"s1","isSidechain":true,"timestamp":"T2","message":{"role":"user","content":"rewritten first"}}
+{"type":"user","uuid":"s2","isSidechain":true,"timestamp":"T3","message":{"role":"user","content":"second"}}
+{"type":"assistant","uuid":"s3","parentUuid":"s1","isSidechain":true,"timestamp":"T4","message":{"id":"s3","role":"assistant","content":[{"type":"text","text":"answer one"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{IncludeTools: true})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Len(t, msgs[0].Blocks, 2)
+	require.Len(t, msgs[0].Blocks[0].Sidechain, 2)
+	assert.Equal(t, "answer one", msgs[0].Blocks[0].Sidechain[1].Blocks[0].Text)
+	require.Len(t, msgs[0].Blocks[1].Sidechain, 1)
+	assert.Equal(t, "second", msgs[0].Blocks[1].Sidechain[0].Blocks[0].Text)
+}
+
+func TestParseSession_SidechainFromSubagentsDir(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "sess.jsonl",
+		`{"type":"assistant","uuid":"a1","timestamp":"T1","message":{"id":"a1","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Agent","input":{"prompt":"Explore"}}]}}
+`)
+	writeTempFile(t, dir, "sess/subagents/agent-1.jsonl",
+		`{"type":"user","uuid":"s1","timestamp":"T2","message":{"role":"user","content":"Explore"}}
+{"type":"assistant","uuid":"s2","parentUuid":"s1","timestamp":"T3","message":{"id":"s2","role":"assistant","content":[{"type":"text","text":"Explored"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{IncludeTools: true})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Len(t, msgs[0].Blocks[0].Sidechain, 2)
+}
+
+func TestParseSession_SidechainExcludedFromMainThread(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","uuid":"u1","timestamp":"T1","message":{"role":"user","content":"main"}}
+{"type":"user","uuid":"s1","isSidechain":true,"timestamp":"T2","message":{"role":"user","content":"side"}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "main", msgs[0].Blocks[0].Text)
+}
+
+func TestParseSession_FollowsActiveBranch(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","uuid":"u1","timestamp":"T1","message":{"role":"user","content":"Original question"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","timestamp":"T2","message":{"id":"a1","role":"assistant","content":[{"type":"text","text":"Original answer"}]}}
+{"type":"user","uuid":"u2","parentUuid":"a1","timestamp":"T3","message":{"role":"user","content":"First follow-up"}}
+{"type":"assistant","uuid":"a2","parentUuid":"u2","timestamp":"T4","message":{"id":"a2","role":"assistant","content":[{"type":"text","text":"First reply"}]}}
+{"type":"user","uuid":"u3","parentUuid":"a1","timestamp":"T5","message":{"role":"user","content":"Edited follow-up"}}
+{"type":"assistant","uuid":"a3","parentUuid":"u3","timestamp":"T6","message":{"id":"a3","role":"assistant","content":[{"type":"text","text":"Edited reply"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 4)
+	assert.Equal(t, "Original question", msgs[0].Blocks[0].Text)
+	assert.Equal(t, "Original answer", msgs[1].Blocks[0].Text)
+	assert.Equal(t, "Edited follow-up", msgs[2].Blocks[0].Text)
+	assert.Equal(t, "Edited reply", msgs[3].Blocks[0].Text)
+
+	require.Len(t, msgs[2].Branches, 1)
+	abandoned := msgs[2].Branches[0]
+	require.Len(t, abandoned, 2)
+	assert.Equal(t, "First follow-up", abandoned[0].Blocks[0].Text)
+	assert.Equal(t, "First reply", abandoned[1].Blocks[0].Text)
+	assert.Empty(t, msgs[0].Branches)
+}
+
+func TestParseSession_EditedFirstPrompt(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","uuid":"u1","parentUuid":null,"timestamp":"T1","message":{"role":"user","content":"First attempt"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","timestamp":"T2","message":{"id":"a1","role":"assistant","content":[{"type":"text","text":"First answer"}]}}
+{"type":"user","uuid":"u2","parentUuid":null,"timestamp":"T3","message":{"role":"user","content":"Edited prompt"}}
+{"type":"assistant","uuid":"a2","parentUuid":"u2","timestamp":"T4","message":{"id":"a2","role":"assistant","content":[{"type":"text","text":"Edited answer"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "Edited prompt", msgs[0].Blocks[0].Text)
+	assert.Equal(t, "Edited answer", msgs[1].Blocks[0].Text)
+
+	require.Len(t, msgs[0].Branches, 1)
+	abandoned := msgs[0].Branches[0]
+	require.Len(t, abandoned, 2)
+	assert.Equal(t, "First attempt", abandoned[0].Blocks[0].Text)
+	assert.Equal(t, "First answer", abandoned[1].Blocks[0].Text)
+}
+
+func TestParseSession_NestedBranches(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","uuid":"u1","timestamp":"T1","message":{"role":"user","content":"Q1"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","timestamp":"T2","message":{"id":"a1","role":"assistant","content":[{"type":"text","text":"A1 first try"}]}}
+{"type":"user","uuid":"u2","parentUuid":"a1","timestamp":"T3","message":{"role":"user","content":"Q2"}}
+{"type":"assistant","uuid":"a2","parentUuid":"u2","timestamp":"T4","message":{"id":"a2","role":"assistant","content":[{"type":"text","text":"A2 first try"}]}}
+{"type":"assistant","uuid":"a3","parentUuid":"u2","timestamp":"T5","message":{"id":"a3","role":"assistant","content":[{"type":"text","text":"A2 retry"}]}}
+{"type":"assistant","uuid":"a4","parentUuid":"u1","timestamp":"T6","message":{"id":"a4","role":"assistant","content":[{"type":"text","text":"A1 retry"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "A1 retry", msgs[1].Blocks[0].Text)
+	require.Len(t, msgs[1].Branches, 1)
+
+	abandoned := msgs[1].Branches[0]
+	require.Len(t, abandoned, 3)
+	assert.Equal(t, "A1 first try", abandoned[0].Blocks[0].Text)
+	assert.Equal(t, "A2 retry", abandoned[2].Blocks[0].Text)
+	require.Len(t, abandoned[2].Branches, 1)
+	assert.Equal(t, "A2 first try", abandoned[2].Branches[0][0].Blocks[0].Text)
+}
+
+func TestParseSession_IgnoresForksWithoutConversation(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","uuid":"u1","timestamp":"T1","message":{"role":"user","content":"hello"}}
+{"type":"progress","uuid":"p1","parentUuid":"u1"}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","timestamp":"T2","message":{"id":"a1","role":"assistant","content":[{"type":"text","text":"hi"}]}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Empty(t, msgs[1].Branches)
+}
+
+func TestParseSession_FollowsLogicalParentAcrossCompaction(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","uuid":"u1","timestamp":"T1","message":{"role":"user","content":"before compact"}}
+{"type":"system","uuid":"c1","logicalParentUuid":"u1","subtype":"compact_boundary"}
+{"type":"user","uuid":"u2","parentUuid":"c1","timestamp":"T2","message":{"role":"user","content":"after compact"}}
+`)
+
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "before compact", msgs[0].Blocks[0].Text)
+	assert.Equal(t, "after compact", msgs[1].Blocks[0].Text)
+}
+
 func TestExtractToolResultContent_String(t *testing.T) {
 	assert.Equal(t, "hello", extractToolResultContent([]byte(`"hello"`)))
 }
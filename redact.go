@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactRule is a named pattern whose matches are replaced before rendering.
+// If the pattern has a capturing group, only the first group is replaced, so
+// `password=(\S+)` keeps the key and hides the value.
+type RedactRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// RedactConfig is the format of the file passed to --redact-config.
+type RedactConfig struct {
+	Patterns []RedactRule `json:"patterns"`
+	Disable  []string     `json:"disable"` // names of built-in rules to turn off
+}
+
+var builtinRedactRules = []RedactRule{
+	{"private-key", `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	{"anthropic-api-key", `\bsk-ant-[A-Za-z0-9_\-]{20,}`},
+	{"openai-api-key", `\bsk-(?:proj-)?[A-Za-z0-9_\-]{20,}`},
+	{"aws-access-key", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{"aws-secret-key", `(?i)aws_?secret_?access_?key\W{1,4}([A-Za-z0-9/+=]{40})`},
+	{"github-token", `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`},
+	{"gitlab-token", `\bglpat-[A-Za-z0-9_\-]{20,}`},
+	{"slack-token", `\bxox[abprs]-[A-Za-z0-9\-]{10,}`},
+	{"google-api-key", `\bAIza[0-9A-Za-z_\-]{35}`},
+	{"stripe-key", `\b[rs]k_(?:live|test)_[0-9A-Za-z]{20,}`},
+	{"jwt", `\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`},
+	{"bearer-token", `(?i)\bbearer\s+([A-Za-z0-9._~+/\-]{20,}=*)`},
+	{"url-password", `\b[a-z][a-z0-9+.\-]*://[^/\s:@]+:([^/\s@]+)@`},
+	{"secret-assignment", `(?i)\b(?:password|passwd|secret|api[_\-]?key|access[_\-]?token)\s*[:=]\s*["']?([^\s"',;\[][^\s"',;]{7,})`},
+	// Hosts under suffixes used for private networks. A name followed by a
+	// file extension, like settings.local.json, is left alone.
+	{"internal-host", `(?i)\b((?:[a-z0-9](?:[a-z0-9\-]*[a-z0-9])?\.)+(?:internal|corp|intranet|lan|local))(?:[^\w.\-]|\.\W|\.$|$)`},
+	{"email", `\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`},
+}
+
+type redactRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor is safe for concurrent use, so one instance can total the
+// matches of a whole batch export.
+type Redactor struct {
+	rules []redactRule
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func LoadRedactConfig(path string) (RedactConfig, error) {
+	var cfg RedactConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read redact config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse redact config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func NewRedactor(cfg RedactConfig) (*Redactor, error) {
+	disabled := make(map[string]bool, len(cfg.Disable))
+	for _, name := range cfg.Disable {
+		disabled[name] = true
+	}
+
+	r := &Redactor{counts: make(map[string]int)}
+	for _, rule := range append(append([]RedactRule{}, builtinRedactRules...), cfg.Patterns...) {
+		if disabled[rule.Name] {
+			continue
+		}
+		if rule.Name == "" {
+			rule.Name = "custom"
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %s: %w", rule.Name, err)
+		}
+		r.rules = append(r.rules, redactRule{name: rule.Name, re: re})
+	}
+	return r, nil
+}
+
+// RedactMessages redacts every block in msgs in place.
+func (r *Redactor) RedactMessages(msgs []Message) {
+	walkBlocks(msgs, func(b *ContentBlock) {
+		b.Text = r.RedactString(b.Text)
+		b.ToolInput = r.redactJSON(b.ToolInput)
+	})
+}
+
+func (r *Redactor) RedactString(s string) string {
+	for _, rule := range r.rules {
+		matches := rule.re.FindAllStringSubmatchIndex(s, -1)
+		if matches == nil {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			b.WriteString(s[last:start])
+			b.WriteString("[REDACTED:" + rule.name + "]")
+			last = end
+			r.mu.Lock()
+			r.counts[rule.name]++
+			r.mu.Unlock()
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// redactJSON redacts the string values of a JSON document, so patterns can't
+// match across quotes or escapes and leave invalid JSON behind.
+func (r *Redactor) redactJSON(input string) string {
+	return mapJSONStrings(input, r.RedactString)
+}
+
+// mapJSONStrings applies fn to every string value in a JSON document. The
+// input is returned verbatim if fn changes nothing, and input that isn't JSON
+// is passed to fn as plain text.
+func mapJSONStrings(input string, fn func(string) string) string {
+	if input == "" {
+		return input
+	}
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fn(input)
+	}
+
+	changed := false
+	var walk func(any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			mapped := fn(v)
+			changed = changed || mapped != v
+			return mapped
+		case map[string]any:
+			for k, e := range v {
+				v[k] = walk(e)
+			}
+		case []any:
+			for i, e := range v {
+				v[i] = walk(e)
+			}
+		}
+		return v
+	}
+	v = walk(v)
+	if !changed {
+		return input
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fn(input)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// Report writes how many matches each rule redacted.
+func (r *Redactor) Report(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.counts) == 0 {
+		fmt.Fprintln(w, "Redaction: nothing found")
+		return
+	}
+	names := make([]string, 0, len(r.counts))
+	total := 0
+	for name, n := range r.counts {
+		names = append(names, name)
+		total += n
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "Redaction: replaced %d match(es)\n", total)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-20s %d\n", name, r.counts[name])
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"html"
 	"html/template"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/chroma/v2"
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
@@ -15,6 +18,7 @@ import (
 	"github.com/gomarkdown/markdown"
 	mkhtml "github.com/gomarkdown/markdown/html"
 	"github.com/gomarkdown/markdown/parser"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 type SessionMeta struct {
@@ -23,28 +27,254 @@ type SessionMeta struct {
 	Date         string
 	MessageCount int
 	FirstPrompt  string
+	SessionDetails
+}
+
+// SessionDetails describe the environment a session ran in, as recorded in
+// its rows.
+type SessionDetails struct {
+	Cwd       string
+	GitBranch string
+	Version   string   // Claude Code version
+	Models    []string // in order of first use
 }
 
 type RenderOpts struct {
 	IncludeTools    bool
 	IncludeThinking bool
+	Theme           string // "dark" (default), "light" or "auto"
+	LiveURL         string // event stream the page reloads itself from
+	Nav             *PageNav
+	Usage           *UsageReport   // shown in the page header if set
+	Location        *time.Location // time zone of message times; nil means local
+}
+
+// PageNav links a session page to its neighbours in a static site.
+type PageNav struct {
+	Index     string
+	Prev      string
+	PrevTitle string
+	Next      string
+	NextTitle string
+}
+
+// chromaStyles maps each page theme to the chroma style used for code.
+var chromaStyles = map[string]string{
+	"dark":  "monokai",
+	"light": "github",
+}
+
+type renderedBlock struct {
+	Type       string
+	HTML       template.HTML
+	ToolName   string
+	IsError    bool
+	HasResult  bool
+	ResultHTML template.HTML
+	Custom     bool   // HTML came from a tool-specific renderer and includes the result
+	Summary    string // short description of the call shown in the card header
+	Sidechain  []renderedMessage
+	Images     []renderedImage // the image of an image block, or those a tool returned
+}
+
+// renderedImage is an image embedded as a data URI. Src is empty if the
+// image was stripped.
+type renderedImage struct {
+	Src       template.URL
+	MediaType string
+}
+
+func renderImages(images []ContentBlock) []renderedImage {
+	var rendered []renderedImage
+	for _, img := range images {
+		ri := renderedImage{MediaType: img.MediaType}
+		if img.Data != "" {
+			ri.Src = template.URL("data:" + img.MediaType + ";base64," + img.Data)
+		}
+		rendered = append(rendered, ri)
+	}
+	return rendered
+}
+
+type renderedMessage struct {
+	Role     string
+	Blocks   []renderedBlock
+	Subagent bool
+	Branches [][]renderedMessage // set on a fork placeholder; the last entry is the active branch
+	Model    string              // set when the model differs from the one before
+	Time     string              // e.g. "15:04", or "Jan 3, 09:12" on a new day
+	DateTime string              // RFC 3339, shown on hover
+	Gap      string              // e.g. "+4m 12s", set after a pause of minGap or more
+}
+
+// renderState is carried from message to message while rendering a thread.
+type renderState struct {
+	loc   *time.Location
+	model string    // model that answered last
+	last  time.Time // time of the last rendered message
 }
 
 func RenderHTML(messages []Message, meta SessionMeta, opts RenderOpts) (string, error) {
-	type renderedBlock struct {
-		Type     string
-		HTML     template.HTML
-		ToolName string
-		IsError  bool
+	tmpl, err := parseTemplates()
+	if err != nil {
+		return "", err
+	}
+
+	theme := opts.Theme
+	if theme == "" {
+		theme = "dark"
+	}
+	chromaCSS, err := themeChromaCSS(theme)
+	if err != nil {
+		return "", err
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
 	}
-	type renderedMessage struct {
-		Role   string
-		Blocks []renderedBlock
+	var duration string
+	if d := sessionDuration(messages); d >= time.Second {
+		duration = formatDuration(d)
 	}
 
+	data := struct {
+		Meta      SessionMeta
+		Messages  []renderedMessage
+		Theme     string
+		ChromaCSS template.CSS
+		LiveURL   string
+		Nav       *PageNav
+		Usage     *UsageReport
+		Duration  string
+	}{
+		Meta:      meta,
+		Messages:  renderMessages(messages, renderState{loc: loc}),
+		Theme:     theme,
+		ChromaCSS: template.CSS(chromaCSS),
+		LiveURL:   opts.LiveURL,
+		Nav:       opts.Nav,
+		Usage:     opts.Usage,
+		Duration:  duration,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("execute template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// parseTemplates parses the session page together with the other pages that
+// share its styles and partials.
+func parseTemplates() (*template.Template, error) {
+	tmpl, err := template.New("page").Funcs(template.FuncMap{
+		"inc":     func(i int) int { return i + 1 },
+		"tokens":  formatTokens,
+		"cost":    formatCost,
+		"percent": func(f float64) float64 { return f * 100 },
+		"join":    strings.Join,
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	if _, err := tmpl.New("index").Parse(indexTemplate); err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	if _, err := tmpl.New("report").Parse(reportTemplate); err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// validTheme reports whether theme is one of the page themes: dark, light
+// or auto.
+func validTheme(theme string) bool {
+	_, ok := chromaStyles[theme]
+	return ok || theme == "auto"
+}
+
+// themeChromaCSS returns the stylesheet for highlighted code in theme. In
+// auto mode the light style is scoped so it applies under the same
+// conditions as the light page palette.
+func themeChromaCSS(theme string) (string, error) {
+	if theme == "auto" {
+		dark, err := chromaCSS(chromaStyles["dark"], "")
+		if err != nil {
+			return "", err
+		}
+		preferLight, err := chromaCSS(chromaStyles["light"], ":root:not([data-theme=dark])")
+		if err != nil {
+			return "", err
+		}
+		forceLight, err := chromaCSS(chromaStyles["light"], ":root[data-theme=light]")
+		if err != nil {
+			return "", err
+		}
+		return dark + "@media(prefers-color-scheme:light){\n" + preferLight + "}\n" + forceLight, nil
+	}
+	name, ok := chromaStyles[theme]
+	if !ok {
+		return "", fmt.Errorf("unknown theme %q", theme)
+	}
+	return chromaCSS(name, "")
+}
+
+func chromaCSS(styleName, scope string) (string, error) {
+	var buf bytes.Buffer
+	formatter := chromahtml.New(chromahtml.WithClasses(true), chromahtml.WithCSSComments(false))
+	if err := formatter.WriteCSS(&buf, styles.Get(styleName)); err != nil {
+		return "", fmt.Errorf("write chroma css: %w", err)
+	}
+	if scope == "" {
+		return buf.String(), nil
+	}
+	var scoped strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		scoped.WriteString(scope + " " + line + "\n")
+	}
+	return scoped.String(), nil
+}
+
+// renderMessages renders messages for the template, continuing from st. An
+// assistant message from a different model than the last one shown is marked
+// with its model name.
+func renderMessages(messages []Message, st renderState) []renderedMessage {
 	var rendered []renderedMessage
-	for _, msg := range messages {
+	for i, msg := range messages {
+		if len(msg.Branches) > 0 {
+			active := append([]Message{}, messages[i:]...)
+			active[0].Branches = nil
+			fork := renderedMessage{Role: "fork"}
+			for _, alt := range msg.Branches {
+				fork.Branches = append(fork.Branches, renderMessages(alt, st))
+			}
+			fork.Branches = append(fork.Branches, renderMessages(active, st))
+			return append(rendered, fork)
+		}
+
 		rm := renderedMessage{Role: msg.Role}
+		prevModel := st.model
+		if msg.Role == "assistant" && msg.Model != "" && msg.Model != syntheticModel {
+			if st.model != "" && msg.Model != st.model {
+				rm.Model = msg.Model
+			}
+			st.model = msg.Model
+		}
+		prev := st.last
+		if ts, ok := parseTimestamp(msg.Timestamp); ok {
+			local := ts.In(st.loc)
+			rm.Time = local.Format("15:04")
+			rm.DateTime = local.Format(time.RFC3339)
+			if !prev.IsZero() {
+				if !sameDay(prev.In(st.loc), local) {
+					rm.Time = local.Format("Jan 2, 15:04")
+				}
+				if gap := ts.Sub(prev); gap >= minGap {
+					rm.Gap = "+" + formatDuration(gap)
+				}
+			}
+			st.last = ts
+		}
 		hasVisible := false
 		for _, b := range msg.Blocks {
 			switch b.Type {
@@ -61,47 +291,424 @@ func RenderHTML(messages []Message, meta SessionMeta, opts RenderOpts) (string,
 				})
 				hasVisible = true
 			case "tool_use":
-				highlighted := highlightJSON(b.ToolInput)
-				rm.Blocks = append(rm.Blocks, renderedBlock{
-					Type:     "tool_use",
-					ToolName: b.ToolName,
-					HTML:     template.HTML(highlighted),
-				})
+				sidechain := renderMessages(b.Sidechain, st)
+				for i := range sidechain {
+					sidechain[i].Subagent = true
+				}
+				rb := renderedBlock{
+					Type:      "tool_use",
+					ToolName:  b.ToolName,
+					Sidechain: sidechain,
+				}
+				if b.Result != nil {
+					rb.HasResult = true
+					rb.IsError = b.Result.IsError
+					rb.Images = renderImages(b.Result.Images)
+				}
+				if view, ok := renderToolView(b); ok {
+					rb.Custom = true
+					rb.Summary = view.Summary
+					rb.HTML = template.HTML(view.Body)
+				} else {
+					rb.HTML = template.HTML(highlightJSON(b.ToolInput))
+					if b.Result != nil {
+						rb.ResultHTML = template.HTML(renderToolOutput(b.Result.Text))
+					}
+				}
+				rm.Blocks = append(rm.Blocks, rb)
 				hasVisible = true
 			case "tool_result":
 				rm.Blocks = append(rm.Blocks, renderedBlock{
-					Type:    "tool_result",
-					HTML:    template.HTML("<pre class=\"tool-output\">" + html.EscapeString(truncate(b.Text, 2000)) + "</pre>"),
-					IsError: b.IsError,
+					Type:     "tool_result",
+					HTML:     template.HTML(renderToolOutput(b.Text)),
+					ToolName: b.ToolName,
+					IsError:  b.IsError,
+					Images:   renderImages(b.Images),
 				})
-				if msg.Role == "assistant" {
+				// A user's result is only worth showing when it answers a
+				// call from before a branch diverged; other orphans are noise.
+				if msg.Role == "assistant" || b.ToolName != "" {
 					hasVisible = true
 				}
+			case "image":
+				rm.Blocks = append(rm.Blocks, renderedBlock{
+					Type:   "image",
+					Images: renderImages([]ContentBlock{b}),
+				})
+				hasVisible = true
 			}
 		}
 		if hasVisible {
 			rendered = append(rendered, rm)
+		} else {
+			st.last, st.model = prev, prevModel
 		}
 	}
+	return rendered
+}
+
+// minGap is the shortest pause before a message that gets marked.
+const minGap = time.Minute
 
-	tmpl, err := template.New("page").Parse(htmlTemplate)
+// parseTimestamp parses a message timestamp, which Claude Code records in
+// RFC 3339.
+func parseTimestamp(s string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// sessionDuration is the time from the first to the last message, counting
+// branches and subagent conversations.
+func sessionDuration(messages []Message) time.Duration {
+	var first, last time.Time
+	var walk func([]Message)
+	walk = func(messages []Message) {
+		for _, msg := range messages {
+			if ts, ok := parseTimestamp(msg.Timestamp); ok {
+				if first.IsZero() || ts.Before(first) {
+					first = ts
+				}
+				if ts.After(last) {
+					last = ts
+				}
+			}
+			for _, alt := range msg.Branches {
+				walk(alt)
+			}
+			for _, b := range msg.Blocks {
+				walk(b.Sidechain)
+			}
+		}
+	}
+	walk(messages)
+	return last.Sub(first)
+}
+
+// formatDuration shows d in its two largest units, e.g. 1h 5m, 4m 12s or 9s.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, sec := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh %dm", h, m)
+	case m > 0:
+		return fmt.Sprintf("%dm %ds", m, sec)
+	}
+	return fmt.Sprintf("%ds", sec)
+}
+
+// toolView is the tool-specific rendering of a tool call and its result.
+type toolView struct {
+	Summary string
+	Body    string
+}
+
+// toolRenderer renders a tool call from its decoded input. It returns false
+// when the input doesn't have the shape it expects, in which case the generic
+// JSON view is used instead.
+type toolRenderer func(input map[string]any, result *ContentBlock) (toolView, bool)
+
+var toolRenderers = map[string]toolRenderer{
+	"Edit":      renderEditTool,
+	"MultiEdit": renderMultiEditTool,
+	"Write":     renderWriteTool,
+	"Read":      renderReadTool,
+	"Bash":      renderBashTool,
+	"Grep":      renderGrepTool,
+	"TodoWrite": renderTodoTool,
+}
+
+// toolSummaries describe a tool call in a few words for its card header.
+// They take the same decoded input as toolRenderers but do none of the
+// rendering work.
+var toolSummaries = map[string]func(input map[string]any) string{
+	"Edit":      filePathSummary,
+	"MultiEdit": multiEditSummary,
+	"Write":     filePathSummary,
+	"Read":      filePathSummary,
+	"Bash":      bashSummary,
+	"Grep":      grepSummary,
+	"TodoWrite": todoSummary,
+}
+
+// toolSummary returns the short description of a tool call, or "" for
+// tools without one.
+func toolSummary(b ContentBlock) string {
+	summarize, ok := toolSummaries[b.ToolName]
+	if !ok {
+		return ""
+	}
+	var input map[string]any
+	if err := json.Unmarshal([]byte(b.ToolInput), &input); err != nil {
+		return ""
+	}
+	return summarize(input)
+}
+
+func filePathSummary(input map[string]any) string {
+	return inputString(input, "file_path")
+}
+
+func multiEditSummary(input map[string]any) string {
+	path := inputString(input, "file_path")
+	edits, ok := input["edits"].([]any)
+	if path == "" || !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s (%d edits)", path, len(edits))
+}
+
+func bashSummary(input map[string]any) string {
+	if summary := inputString(input, "description"); summary != "" {
+		return summary
+	}
+	return firstLine(inputString(input, "command"))
+}
+
+func grepSummary(input map[string]any) string {
+	summary := inputString(input, "pattern")
+	if summary == "" {
+		return ""
+	}
+	if path := inputString(input, "path"); path != "" {
+		summary += " in " + path
+	}
+	if glob := inputString(input, "glob"); glob != "" {
+		summary += " (" + glob + ")"
+	}
+	return summary
+}
+
+func todoSummary(input map[string]any) string {
+	todos, ok := input["todos"].([]any)
+	if !ok {
+		return ""
+	}
+	done := 0
+	for _, t := range todos {
+		if todo, ok := t.(map[string]any); ok && inputString(todo, "status") == "completed" {
+			done++
+		}
+	}
+	return fmt.Sprintf("%d/%d done", done, len(todos))
+}
+
+const maxFileViewLines = 400
+
+func renderToolView(b ContentBlock) (toolView, bool) {
+	render, ok := toolRenderers[b.ToolName]
+	if !ok {
+		return toolView{}, false
+	}
+	var input map[string]any
+	if err := json.Unmarshal([]byte(b.ToolInput), &input); err != nil {
+		return toolView{}, false
+	}
+	return render(input, b.Result)
+}
+
+func inputString(input map[string]any, key string) string {
+	s, _ := input[key].(string)
+	return s
+}
+
+func renderEditTool(input map[string]any, result *ContentBlock) (toolView, bool) {
+	path := inputString(input, "file_path")
+	if path == "" {
+		return toolView{}, false
+	}
+	body := renderFileHeader(path) + renderDiff(path, inputString(input, "old_string"), inputString(input, "new_string"))
+	if all, _ := input["replace_all"].(bool); all {
+		body += `<div class="tool-note">All occurrences replaced</div>`
+	}
+	return toolView{Summary: path, Body: body + renderErrorResult(result)}, true
+}
+
+func renderMultiEditTool(input map[string]any, result *ContentBlock) (toolView, bool) {
+	path := inputString(input, "file_path")
+	edits, ok := input["edits"].([]any)
+	if path == "" || !ok {
+		return toolView{}, false
+	}
+	body := renderFileHeader(path)
+	for _, e := range edits {
+		edit, ok := e.(map[string]any)
+		if !ok {
+			return toolView{}, false
+		}
+		body += renderDiff(path, inputString(edit, "old_string"), inputString(edit, "new_string"))
+	}
+	return toolView{Summary: multiEditSummary(input), Body: body + renderErrorResult(result)}, true
+}
+
+func renderWriteTool(input map[string]any, result *ContentBlock) (toolView, bool) {
+	path := inputString(input, "file_path")
+	content, ok := input["content"].(string)
+	if path == "" || !ok {
+		return toolView{}, false
+	}
+	return toolView{Summary: path, Body: renderFileHeader(path) + renderFileContents(path, content, 1) + renderErrorResult(result)}, true
+}
+
+var readLineRe = regexp.MustCompile(`^\s*(\d+)(?:→|\t)(.*)$`)
+
+func renderReadTool(input map[string]any, result *ContentBlock) (toolView, bool) {
+	path := inputString(input, "file_path")
+	if path == "" {
+		return toolView{}, false
+	}
+	view := toolView{Summary: path, Body: renderFileHeader(path)}
+	if result == nil {
+		return view, true
+	}
+	if result.IsError {
+		view.Body += renderErrorResult(result)
+		return view, true
+	}
+
+	// Read results are numbered like `cat -n`; strip the numbers so the
+	// contents can be highlighted, and let chroma number the lines instead.
+	var lines []string
+	firstLine := 0
+	for _, line := range strings.Split(result.Text, "\n") {
+		m := readLineRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if firstLine == 0 {
+			fmt.Sscanf(m[1], "%d", &firstLine)
+		}
+		lines = append(lines, m[2])
+	}
+	if len(lines) == 0 {
+		view.Body += renderToolOutput(result.Text)
+		return view, true
+	}
+	view.Body += renderFileContents(path, strings.Join(lines, "\n"), firstLine)
+	return view, true
+}
+
+func renderBashTool(input map[string]any, result *ContentBlock) (toolView, bool) {
+	command := inputString(input, "command")
+	if command == "" {
+		return toolView{}, false
+	}
+	body := `<div class="terminal"><pre class="terminal-cmd"><span class="terminal-prompt">$ </span>` + html.EscapeString(command) + `</pre>`
+	if result != nil && result.Text != "" {
+		class := "terminal-output"
+		if result.IsError {
+			class += " error"
+		}
+		body += `<pre class="` + class + `">` + html.EscapeString(truncate(result.Text, 2000)) + `</pre>`
+	}
+	body += `</div>`
+	return toolView{Summary: bashSummary(input), Body: body}, true
+}
+
+func renderGrepTool(input map[string]any, result *ContentBlock) (toolView, bool) {
+	summary := grepSummary(input)
+	if summary == "" {
+		return toolView{}, false
+	}
+	body := `<div class="tool-file"><span class="tool-file-label">grep</span> ` + html.EscapeString(summary) + `</div>`
+	if result != nil {
+		body += renderToolOutput(result.Text)
+	}
+	return toolView{Summary: summary, Body: body}, true
+}
+
+func renderTodoTool(input map[string]any, result *ContentBlock) (toolView, bool) {
+	todos, ok := input["todos"].([]any)
+	if !ok {
+		return toolView{}, false
+	}
+	var b strings.Builder
+	b.WriteString(`<ul class="todo-list">`)
+	for _, t := range todos {
+		todo, ok := t.(map[string]any)
+		if !ok {
+			return toolView{}, false
+		}
+		status := inputString(todo, "status")
+		mark := "○"
+		switch status {
+		case "completed":
+			mark = "✓"
+		case "in_progress":
+			mark = "◐"
+		}
+		fmt.Fprintf(&b, `<li class="todo todo-%s"><span class="todo-mark">%s</span>%s</li>`,
+			html.EscapeString(status), mark, html.EscapeString(inputString(todo, "content")))
+	}
+	b.WriteString(`</ul>`)
+	return toolView{Summary: todoSummary(input), Body: b.String() + renderErrorResult(result)}, true
+}
+
+func renderFileHeader(path string) string {
+	return `<div class="tool-file"><span class="tool-file-label">file</span> ` + html.EscapeString(path) + `</div>`
+}
+
+func renderFileContents(path, content string, firstLine int) string {
+	lines := strings.Split(content, "\n")
+	more := ""
+	if len(lines) > maxFileViewLines {
+		more = fmt.Sprintf(`<div class="tool-note">… %d more lines</div>`, len(lines)-maxFileViewLines)
+		content = strings.Join(lines[:maxFileViewLines], "\n")
+	}
+	highlighted, err := highlightFile(content, path, firstLine)
 	if err != nil {
-		return "", fmt.Errorf("parse template: %w", err)
+		return renderToolOutput(content) + more
 	}
+	return highlighted + more
+}
 
-	data := struct {
-		Meta     SessionMeta
-		Messages []renderedMessage
-	}{
-		Meta:     meta,
-		Messages: rendered,
+func renderDiff(path, oldText, newText string) string {
+	diff := unifiedDiff(path, oldText, newText)
+	highlighted, err := highlightCode(diff, "diff")
+	if err != nil {
+		return renderToolOutput(diff)
 	}
+	return highlighted
+}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("execute template: %w", err)
+func unifiedDiff(path, oldText, newText string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(oldText),
+		B:        difflib.SplitLines(newText),
+		FromFile: path,
+		ToFile:   path,
+		Context:  3,
+	})
+	if err != nil {
+		return oldText + "\n→\n" + newText
 	}
-	return buf.String(), nil
+	return diff
+}
+
+// renderErrorResult shows a result only when it's an error; successful
+// results of file tools just repeat what the input already shows.
+func renderErrorResult(result *ContentBlock) string {
+	if result == nil || !result.IsError {
+		return ""
+	}
+	return `<div class="tool-section">Error</div>` + renderToolOutput(result.Text)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " …"
+	}
+	return s
+}
+
+func renderToolOutput(text string) string {
+	return "<pre class=\"tool-output\">" + html.EscapeString(truncate(text, 2000)) + "</pre>"
 }
 
 var codeBlockRe = regexp.MustCompile(`<pre><code class="language-(\w+)">([\s\S]*?)</code></pre>`)
@@ -134,17 +741,26 @@ func renderMarkdown(text string) string {
 }
 
 func highlightCode(code, lang string) (string, error) {
-	lexer := lexers.Get(lang)
+	return highlightWith(lexers.Get(lang), code)
+}
+
+func highlightFile(code, path string, firstLine int) (string, error) {
+	return highlightWith(lexers.Match(filepath.Base(path)), code,
+		chromahtml.WithLineNumbers(true),
+		chromahtml.BaseLineNumber(firstLine),
+	)
+}
+
+func highlightWith(lexer chroma.Lexer, code string, extra ...chromahtml.Option) (string, error) {
 	if lexer == nil {
 		lexer = lexers.Fallback
 	}
 	lexer = chroma.Coalesce(lexer)
 
-	style := styles.Get("monokai")
-	formatter := chromahtml.New(
-		chromahtml.WithClasses(false),
+	formatter := chromahtml.New(append([]chromahtml.Option{
+		chromahtml.WithClasses(true),
 		chromahtml.PreventSurroundingPre(false),
-	)
+	}, extra...)...)
 
 	iterator, err := lexer.Tokenise(nil, code)
 	if err != nil {
@@ -152,7 +768,7 @@ func highlightCode(code, lang string) (string, error) {
 	}
 
 	var buf bytes.Buffer
-	if err := formatter.Format(&buf, style, iterator); err != nil {
+	if err := formatter.Format(&buf, styles.Fallback, iterator); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
@@ -194,67 +810,69 @@ const htmlTemplate = `<!DOCTYPE html>
 <meta charset="UTF-8">
 <meta name="viewport" content="width=device-width, initial-scale=1.0">
 <title>Claude Code Share{{if .Meta.Project}} — {{.Meta.Project}}{{end}}</title>
-<link rel="icon" type="image/svg+xml" href="data:image/svg+xml,<svg xmlns='http://www.w3.org/2000/svg' viewBox='0 0 16 16'><path fill='%23D97757' d='m3.127 10.604 3.135-1.76.053-.153-.053-.085H6.11l-.525-.032-1.791-.048-1.554-.065-1.505-.08-.38-.081L0 7.832l.036-.234.32-.214.455.04 1.009.069 1.513.105 1.097.064 1.626.17h.259l.036-.105-.089-.065-.068-.064-1.566-1.062-1.695-1.121-.887-.646-.48-.327-.243-.306-.104-.67.435-.48.585.04.15.04.593.456 1.267.981 1.654 1.218.242.202.097-.068.012-.049-.109-.181-.9-1.626-.96-1.655-.428-.686-.113-.411a2 2 0 0 1-.068-.484l.496-.674L4.446 0l.662.089.279.242.411.94.666 1.48 1.033 2.014.302.597.162.553.06.17h.105v-.097l.085-1.134.157-1.392.154-1.792.052-.504.25-.605.497-.327.387.186.319.456-.045.294-.19 1.23-.37 1.93-.243 1.29h.142l.161-.16.654-.868 1.097-1.372.484-.545.565-.601.363-.287h.686l.505.751-.226.775-.707.895-.585.759-.839 1.13-.524.904.048.072.125-.012 1.897-.403 1.024-.186 1.223-.21.553.258.06.263-.218.536-1.307.323-1.533.307-2.284.54-.028.02.032.04 1.029.098.44.024h1.077l2.005.15.525.346.315.424-.053.323-.807.411-3.631-.863-.872-.218h-.12v.073l.726.71 1.331 1.202 1.667 1.55.084.383-.214.302-.226-.032-1.464-1.101-.565-.497-1.28-1.077h-.084v.113l.295.432 1.557 2.34.08.718-.112.234-.404.141-.444-.08-.911-1.28-.94-1.44-.759-1.291-.093.053-.448 4.821-.21.246-.484.186-.403-.307-.214-.496.214-.98.258-1.28.21-1.016.19-1.263.112-.42-.008-.028-.092.012-.953 1.307-1.448 1.957-1.146 1.227-.274.109-.477-.247.045-.44.266-.39 1.586-2.018.956-1.25.617-.723-.004-.105h-.036l-4.212 2.736-.75.096-.324-.302.04-.496.154-.162 1.267-.871z'/></svg>">
+{{template "favicon"}}
 <style>
-*,*::before,*::after{box-sizing:border-box;margin:0;padding:0}
-:root{
-  --bg:#1a1a1a;--surface:#262626;--surface-hover:#303030;
-  --border:#333;--text:#e8e8e8;--text-secondary:#999;--text-tertiary:#666;
-  --accent:#D97757;--accent-soft:rgba(217,119,87,.12);
-  --user-bg:#353535;--code-bg:#1e1e1e;--code-header:#2a2a2a;
-  --green:#4ade80;--red:#f87171;--blue:#60a5fa;
-  --radius:12px;--radius-sm:8px;--max-w:780px;
-}
-html{font-size:15px;-webkit-font-smoothing:antialiased;-moz-osx-font-smoothing:grayscale}
-body{background:var(--bg);color:var(--text);font-family:'Inter',system-ui,-apple-system,sans-serif;line-height:1.65;min-height:100vh}
-
-.topbar{position:sticky;top:0;z-index:100;background:rgba(26,26,26,.82);backdrop-filter:blur(20px) saturate(1.4);-webkit-backdrop-filter:blur(20px) saturate(1.4);border-bottom:1px solid var(--border)}
-.topbar-inner{max-width:var(--max-w);margin:0 auto;padding:14px 24px;display:flex;align-items:center;justify-content:space-between}
-.topbar-left{display:flex;align-items:center;gap:12px}
-.logo{display:flex;align-items:center;gap:9px;text-decoration:none;color:var(--text)}
-.logo svg{width:28px;height:28px}
-.logo-text{font-weight:600;font-size:.95rem;letter-spacing:-.01em}
-.logo-badge{font-size:.65rem;font-weight:600;letter-spacing:.04em;text-transform:uppercase;color:var(--accent);background:var(--accent-soft);padding:2px 7px;border-radius:5px;margin-left:2px}
-
+{{template "base-css" .}}
+{{.ChromaCSS}}
 .session-meta{max-width:var(--max-w);margin:0 auto;padding:32px 24px 0}
 .session-title{font-size:1.35rem;font-weight:600;letter-spacing:-.02em;margin-bottom:6px}
 .session-info{display:flex;align-items:center;gap:16px;flex-wrap:wrap;color:var(--text-tertiary);font-size:.78rem}
 .session-info-item{display:flex;align-items:center;gap:5px}
 .session-info-item svg{width:14px;height:14px;opacity:.7}
+.usage-panel{margin-top:12px;border:1px solid var(--border);border-radius:var(--radius-sm);background:var(--surface);font-size:.78rem;color:var(--text-secondary)}
+.usage-panel summary{padding:8px 12px;cursor:pointer;user-select:none}
+.usage-table-wrap{overflow-x:auto;padding:0 12px 10px}
+.usage-table{width:100%;border-collapse:collapse;font-variant-numeric:tabular-nums}
+.usage-table th,.usage-table td{padding:4px 8px;text-align:right;white-space:nowrap;border-top:1px solid var(--border)}
+.usage-table th{color:var(--text-tertiary);font-weight:500;border-top:none}
+.usage-table th:first-child,.usage-table td:first-child{text-align:left}
+.usage-total td{font-weight:600;color:var(--text)}
+.page-nav-index{display:inline-block;font-size:.75rem;color:var(--text-tertiary);text-decoration:none;margin-bottom:10px}
+.page-nav-index:hover{color:var(--accent)}
+.page-nav{max-width:var(--max-w);margin:-40px auto 0;padding:0 24px;display:grid;grid-template-columns:1fr 1fr;gap:12px}
+.page-nav-link{display:block;padding:12px 16px;border:1px solid var(--border);border-radius:var(--radius);background:var(--surface);color:var(--text);text-decoration:none;font-size:.82rem;white-space:nowrap;overflow:hidden;text-overflow:ellipsis}
+.page-nav-link:hover{background:var(--surface-hover)}
+.page-nav-next{text-align:right}
+.page-nav-label{display:block;font-size:.68rem;font-weight:600;letter-spacing:.04em;text-transform:uppercase;color:var(--accent)}
 .session-divider{max-width:var(--max-w);margin:24px auto 0;padding:0 24px}
 .session-divider hr{border:none;border-top:1px solid var(--border)}
 
 .messages{max-width:var(--max-w);margin:0 auto;padding:8px 24px 80px}
 
 .msg{padding:24px 0;position:relative}
-.msg+.msg{border-top:1px solid rgba(255,255,255,.04)}
+.msg+.msg{border-top:1px solid var(--hairline)}
 .msg-header{display:flex;align-items:center;gap:10px;margin-bottom:12px}
 .avatar{width:28px;height:28px;border-radius:50%;display:flex;align-items:center;justify-content:center;font-size:.7rem;font-weight:600;flex-shrink:0}
-.avatar-user{background:#444;color:#ccc}
+.avatar-user{background:var(--avatar-user-bg);color:var(--avatar-user)}
 .avatar-assistant{background:var(--accent-soft);color:var(--accent)}
 .avatar-assistant svg{width:16px;height:16px}
 .msg-sender{font-weight:600;font-size:.82rem}
+.msg-time{margin-left:auto;display:flex;align-items:center;gap:8px;font-size:.72rem;color:var(--text-tertiary);font-variant-numeric:tabular-nums;white-space:nowrap}
+.msg-gap{font-family:'JetBrains Mono',monospace;font-size:.68rem;color:var(--text-secondary)}
+.model-badge{font-size:.68rem;font-family:'JetBrains Mono',monospace;color:var(--text-secondary);background:var(--surface);border:1px solid var(--border);padding:1px 7px;border-radius:5px}
 
 .msg-body{padding-left:38px;overflow-wrap:break-word;word-break:break-word}
 .msg-body p{margin-bottom:12px;color:var(--text)}
 .msg-body p:last-child{margin-bottom:0}
-.msg-body strong{font-weight:600;color:#fff}
+.msg-body strong{font-weight:600;color:var(--heading)}
 .msg-body em{color:var(--text-secondary);font-style:italic}
 .msg-body a{color:var(--blue);text-decoration:none}
 .msg-body a:hover{text-decoration:underline}
 .msg-body ul,.msg-body ol{margin:8px 0 12px 20px;color:var(--text-secondary)}
 .msg-body li{margin-bottom:4px}
-.msg-body code:not(pre code){font-family:'JetBrains Mono',monospace;font-size:.85em;background:rgba(255,255,255,.07);padding:2px 6px;border-radius:4px;color:#e0a370}
-.msg-body h1,.msg-body h2,.msg-body h3,.msg-body h4{margin:1em 0 .5em;color:#fff}
+.msg-body code:not(pre code){font-family:'JetBrains Mono',monospace;font-size:.85em;background:var(--inline-code-bg);padding:2px 6px;border-radius:4px;color:var(--inline-code)}
+.msg-body h1,.msg-body h2,.msg-body h3,.msg-body h4{margin:1em 0 .5em;color:var(--heading)}
 .msg-body blockquote{border-left:3px solid var(--accent);padding-left:12px;color:var(--text-secondary);margin:.8em 0}
 .msg-body table{border-collapse:collapse;margin:.8em 0;width:100%}
 .msg-body th,.msg-body td{border:1px solid var(--border);padding:6px 10px;text-align:left}
 .msg-body th{background:var(--surface)}
 .msg-body pre{background:var(--code-bg);border-radius:var(--radius);padding:14px 16px;overflow-x:auto;margin:14px 0;border:1px solid var(--border)}
-.msg-body pre code{font-family:'JetBrains Mono',monospace;font-size:.8rem;line-height:1.7;color:#d4d4d4;background:none;padding:0}
+.msg-body pre code{font-family:'JetBrains Mono',monospace;font-size:.8rem;line-height:1.7;color:var(--code-text);background:none;padding:0}
 
 .msg-user .msg-body{background:var(--user-bg);padding:14px 18px;margin-left:38px;border-radius:var(--radius) var(--radius) var(--radius) 4px;overflow-wrap:break-word;word-break:break-word}
 .msg-user .msg-body p{color:var(--text);margin-bottom:0}
+.msg-image{display:block;max-width:100%;max-height:480px;margin:10px 0;border-radius:var(--radius-sm);border:1px solid var(--border)}
+.image-omitted{display:inline-block;margin:10px 0;padding:6px 12px;border:1px dashed var(--border);border-radius:var(--radius-sm);font-size:.75rem;color:var(--text-tertiary)}
 
 .tool-block{margin:14px 0;border-radius:var(--radius);overflow:hidden;border:1px solid var(--border)}
 .tool-header{display:flex;align-items:center;gap:8px;padding:10px 14px;background:var(--surface);font-size:.78rem;font-weight:500;color:var(--text-secondary);cursor:pointer;user-select:none;transition:background .15s}
@@ -262,16 +880,52 @@ body{background:var(--bg);color:var(--text);font-family:'Inter',system-ui,-apple
 .tool-header svg{width:15px;height:15px;flex-shrink:0}
 .tool-icon{color:var(--accent)}
 .tool-name{font-family:'JetBrains Mono',monospace;font-weight:500;font-size:.75rem}
+.tool-summary{font-family:'JetBrains Mono',monospace;font-size:.72rem;color:var(--text-tertiary);white-space:nowrap;overflow:hidden;text-overflow:ellipsis;min-width:0}
+.tool-file{font-size:.75rem;color:var(--text);margin-bottom:8px;word-break:break-all}
+.tool-file-label{font-family:'Inter',system-ui,-apple-system,sans-serif;font-size:.65rem;font-weight:600;text-transform:uppercase;letter-spacing:.04em;color:var(--accent);background:var(--accent-soft);padding:1px 6px;border-radius:4px;margin-right:4px}
+.tool-note{font-size:.72rem;color:var(--text-tertiary);margin-top:6px}
+.terminal{border-radius:var(--radius-sm);background:var(--terminal-bg);border:1px solid var(--border);overflow:hidden}
+.terminal pre{margin:0;padding:10px 12px;white-space:pre-wrap;word-break:break-word;font-family:'JetBrains Mono',monospace;font-size:.78rem}
+.terminal-cmd{color:var(--text)}
+.terminal-prompt{color:var(--green);user-select:none}
+.terminal-output{color:var(--text-secondary);border-top:1px solid var(--border);max-height:400px;overflow-y:auto}
+.terminal-output.error{color:var(--red)}
+.todo-list{list-style:none;margin:0;padding:0}
+.todo{display:flex;gap:8px;padding:3px 0;color:var(--text)}
+.todo-mark{width:14px;flex-shrink:0;text-align:center;color:var(--text-tertiary)}
+.todo-completed{color:var(--text-tertiary);text-decoration:line-through}
+.todo-completed .todo-mark{color:var(--green);text-decoration:none}
+.todo-in_progress .todo-mark{color:var(--accent)}
 .tool-chevron{margin-left:auto;transition:transform .2s;color:var(--text-tertiary)}
 .tool-chevron.open{transform:rotate(180deg)}
-.tool-body{padding:12px 16px;background:var(--code-bg);border-top:1px solid var(--border);font-family:'JetBrains Mono',monospace;font-size:.78rem;line-height:1.65;color:var(--text-secondary);max-height:300px;overflow-y:auto;display:none}
+.tool-body{padding:12px 16px;background:var(--code-bg);border-top:1px solid var(--border);font-family:'JetBrains Mono',monospace;font-size:.78rem;line-height:1.65;color:var(--text-secondary);max-height:500px;overflow-y:auto;display:none}
 .tool-body.show{display:block}
 .tool-status{display:inline-flex;align-items:center;gap:4px;font-size:.68rem;margin-left:auto;margin-right:8px}
 .tool-status .dot{width:6px;height:6px;border-radius:50%}
 .tool-status .dot.success{background:var(--green)}
 .tool-status .dot.error{background:var(--red)}
+.tool-status .dot.pending{background:var(--text-tertiary)}
+.tool-section{font-family:'Inter',system-ui,-apple-system,sans-serif;font-size:.68rem;font-weight:600;letter-spacing:.04em;text-transform:uppercase;color:var(--text-tertiary);margin:10px 0 6px}
+.tool-section:first-child{margin-top:0}
+
+.subagent{border-top:1px solid var(--border)}
+.subagent-header{display:flex;align-items:center;gap:8px;padding:10px 14px;background:var(--surface);font-size:.78rem;color:var(--text-secondary);cursor:pointer;user-select:none;transition:background .15s}
+.subagent-header:hover{background:var(--surface-hover)}
+.subagent-header svg{width:15px;height:15px;flex-shrink:0}
+.subagent-body{display:none;padding:0 16px;border-left:3px solid var(--accent-soft)}
+.subagent-body.show{display:block}
+.subagent-body .msg{padding:16px 0}
 
-.thinking-block{margin:14px 0;border-radius:var(--radius);border:1px solid rgba(255,255,255,.06);background:rgba(255,255,255,.02)}
+.branch-switcher{display:flex;align-items:center;gap:8px;margin:16px 0 0;padding:8px 12px;border:1px dashed var(--border);border-radius:var(--radius-sm);font-size:.75rem;color:var(--text-secondary)}
+.branch-switcher svg{width:14px;height:14px;color:var(--accent)}
+.branch-label{margin-right:auto}
+.branch-btn{background:var(--surface);border:1px solid var(--border);color:var(--text);border-radius:6px;width:24px;height:24px;cursor:pointer;font-size:.9rem;line-height:1}
+.branch-btn:hover{background:var(--surface-hover)}
+.branch-pos{font-variant-numeric:tabular-nums}
+.branch{display:none}
+.branch.active{display:block}
+
+.thinking-block{margin:14px 0;border-radius:var(--radius);border:1px solid var(--subtle-border);background:var(--subtle)}
 .thinking-header{display:flex;align-items:center;gap:8px;padding:10px 14px;font-size:.78rem;color:var(--text-tertiary);cursor:pointer;user-select:none}
 .thinking-header svg{width:14px;height:14px;opacity:.5}
 .thinking-body{padding:0 14px 12px;font-size:.82rem;line-height:1.65;color:var(--text-tertiary);font-style:italic;display:none}
@@ -280,18 +934,9 @@ body{background:var(--bg);color:var(--text);font-family:'Inter',system-ui,-apple
 .tool-output{background:var(--code-bg);padding:10px;border-radius:4px;font-size:.8rem;white-space:pre-wrap;word-break:break-word;max-height:400px;overflow-y:auto;font-family:'JetBrains Mono',monospace;color:var(--text-secondary)}
 .chroma{background:var(--code-bg)!important;border-radius:var(--radius);padding:14px 16px;overflow-x:auto;border:1px solid var(--border);margin:14px 0}
 
-.footer{text-align:center;padding:40px 24px 32px;font-size:.72rem;color:var(--text-tertiary)}
-.footer a{color:var(--accent);text-decoration:none}
-.footer a:hover{text-decoration:underline}
-
-::-webkit-scrollbar{width:6px;height:6px}
-::-webkit-scrollbar-track{background:transparent}
-::-webkit-scrollbar-thumb{background:#444;border-radius:3px}
-::-webkit-scrollbar-thumb:hover{background:#555}
-
 @media(max-width:640px){
   html{font-size:14px}
-  .topbar-inner,.session-meta,.messages,.session-divider,.footer{padding-left:16px;padding-right:16px}
+  .topbar-inner,.session-meta,.messages,.session-divider,.page-nav,.footer{padding-left:16px;padding-right:16px}
   .msg-body{padding-left:0}
   .msg-user .msg-body{margin-left:0}
   .session-title{font-size:1.15rem}
@@ -308,19 +953,10 @@ body{background:var(--bg);color:var(--text);font-family:'Inter',system-ui,-apple
 </head>
 <body>
 
-<nav class="topbar">
-  <div class="topbar-inner">
-    <div class="topbar-left">
-      <span class="logo">
-        <svg viewBox="0 0 24 24" fill="#D97757" width="28" height="28"><path d="M17.3041 3.541h-3.6718l6.696 16.918H24Zm-10.6082 0L0 20.459h3.7442l1.3693-3.5527h7.0052l1.3693 3.5528h3.7442L10.5363 3.5409Zm-.3712 10.2232 2.2914-5.9456 2.2914 5.9456Z"/></svg>
-        <span class="logo-text">Claude Code</span>
-        <span class="logo-badge">Share</span>
-      </span>
-    </div>
-  </div>
-</nav>
+{{template "topbar" .}}
 
 <div class="session-meta">
+  {{with .Nav}}<a class="page-nav-index" href="{{.Index}}">← All sessions</a>{{end}}
   <h1 class="session-title">{{if .Meta.FirstPrompt}}{{.Meta.FirstPrompt}}{{else}}Claude Conversation{{end}}</h1>
   <div class="session-info">
     {{if .Meta.MessageCount}}<span class="session-info-item">
@@ -335,21 +971,199 @@ body{background:var(--bg);color:var(--text);font-family:'Inter',system-ui,-apple
       <svg fill="none" viewBox="0 0 16 16" stroke="currentColor" stroke-width="1.5"><circle cx="8" cy="8" r="6"/><path d="M6 8h4"/></svg>
       {{.Meta.Date}}
     </span>{{end}}
+    {{with .Duration}}<span class="session-info-item" title="Session duration">
+      <svg fill="none" viewBox="0 0 16 16" stroke="currentColor" stroke-width="1.5"><circle cx="8" cy="8" r="6"/><path d="M8 4.5V8l2.5 1.5"/></svg>
+      {{.}}
+    </span>{{end}}
+    {{with .Meta.Models}}<span class="session-info-item" title="Model{{if gt (len .) 1}}s{{end}}">
+      <svg fill="none" viewBox="0 0 16 16" stroke="currentColor" stroke-width="1.5"><rect x="4" y="4" width="8" height="8" rx="1.5"/><path d="M6 1.5v2.5M10 1.5v2.5M6 12v2.5M10 12v2.5M1.5 6H4M1.5 10H4M12 6h2.5M12 10h2.5"/></svg>
+      {{join . ", "}}
+    </span>{{end}}
+    {{with .Meta.GitBranch}}<span class="session-info-item" title="Git branch">
+      <svg fill="none" viewBox="0 0 16 16" stroke="currentColor" stroke-width="1.5"><circle cx="4.5" cy="3.5" r="1.5"/><circle cx="4.5" cy="12.5" r="1.5"/><circle cx="11.5" cy="5" r="1.5"/><path d="M4.5 5v6M11.5 6.5c0 3-7 2-7 4.5"/></svg>
+      {{.}}
+    </span>{{end}}
+    {{with .Meta.Cwd}}<span class="session-info-item" title="Working directory">
+      <svg fill="none" viewBox="0 0 16 16" stroke="currentColor" stroke-width="1.5"><path d="M2 4.5V12a1 1 0 0 0 1 1h10a1 1 0 0 0 1-1V6a1 1 0 0 0-1-1H8L6.5 3.5H3a1 1 0 0 0-1 1z"/></svg>
+      {{.}}
+    </span>{{end}}
+    {{with .Meta.Version}}<span class="session-info-item" title="Claude Code version">
+      <svg fill="none" viewBox="0 0 16 16" stroke="currentColor" stroke-width="1.5"><path d="M2 8.5V3a1 1 0 0 1 1-1h5.5L14 7.5 7.5 14z"/><circle cx="5.5" cy="5.5" r="1"/></svg>
+      Claude Code {{.}}
+    </span>{{end}}
   </div>
+  {{with .Usage}}<details class="usage-panel">
+    <summary>{{tokens .Total.Usage.InputTokens}} input · {{tokens .Total.Usage.OutputTokens}} output · {{tokens .Total.Usage.CacheReadTokens}} cache read · {{tokens .Total.Usage.CacheCreationTokens}} cache write · {{cost .Total.Cost .Total.Priced}}</summary>
+    <div class="usage-table-wrap"><table class="usage-table">
+      <tr><th>Model</th><th>Messages</th><th>Input</th><th>Output</th><th>Cache read</th><th>Cache write</th><th>Cost</th></tr>
+      {{range .Models}}<tr><td>{{if .Model}}{{.Model}}{{else}}(unknown){{end}}</td><td>{{.Messages}}</td><td>{{tokens .Usage.InputTokens}}</td><td>{{tokens .Usage.OutputTokens}}</td><td>{{tokens .Usage.CacheReadTokens}}</td><td>{{tokens .Usage.CacheCreationTokens}}</td><td>{{cost .Cost .Priced}}</td></tr>
+      {{end}}{{if ne (len .Models) 1}}<tr class="usage-total"><td>Total</td><td>{{.Total.Messages}}</td><td>{{tokens .Total.Usage.InputTokens}}</td><td>{{tokens .Total.Usage.OutputTokens}}</td><td>{{tokens .Total.Usage.CacheReadTokens}}</td><td>{{tokens .Total.Usage.CacheCreationTokens}}</td><td>{{cost .Total.Cost .Total.Priced}}</td></tr>{{end}}
+    </table></div>
+  </details>{{end}}
 </div>
 <div class="session-divider"><hr></div>
 
 <div class="messages">
-{{range .Messages}}
-  {{if eq .Role "user"}}
+{{template "messages" .Messages}}
+</div>
+
+{{with .Nav}}<nav class="page-nav">
+  {{if .Prev}}<a class="page-nav-link" href="{{.Prev}}"><span class="page-nav-label">← Previous</span>{{.PrevTitle}}</a>{{else}}<span></span>{{end}}
+  {{if .Next}}<a class="page-nav-link page-nav-next" href="{{.Next}}"><span class="page-nav-label">Next →</span>{{.NextTitle}}</a>{{end}}
+</nav>{{end}}
+
+{{template "footer"}}
+
+<script>
+{{template "theme-script" .}}function toggleTool(el){
+  var b=el.nextElementSibling;
+  var c=el.querySelector('.tool-chevron');
+  b.classList.toggle('show');
+  c.classList.toggle('open');
+}
+function switchBranch(btn,dir){
+  var g=btn.closest('.branch-group');
+  var bs=Array.prototype.filter.call(g.children,function(c){return c.classList.contains('branch')});
+  var i=bs.findIndex(function(b){return b.classList.contains('active')});
+  var n=(i+dir+bs.length)%bs.length;
+  bs[i].classList.remove('active');
+  bs[n].classList.add('active');
+  g.querySelector('.branch-pos').textContent=(n+1)+' / '+bs.length;
+  g.querySelector('.branch-label').textContent=n===bs.length-1?'Current branch':'Abandoned branch';
+}
+{{if .LiveURL}}(function(){
+  var k='claude-share-scroll';
+  try{var y=sessionStorage.getItem(k);if(y!==null){sessionStorage.removeItem(k);y=+y;scrollTo(0,y<0?document.body.scrollHeight:y)}}catch(e){}
+  new EventSource({{.LiveURL}}).addEventListener('update',function(){
+    var atEnd=innerHeight+scrollY>=document.body.scrollHeight-40;
+    try{sessionStorage.setItem(k,atEnd?-1:scrollY)}catch(e){}
+    location.reload();
+  });
+})();
+{{end}}function toggleThinking(el){
+  var b=el.nextElementSibling;
+  var c=el.querySelector('.tool-chevron');
+  b.classList.toggle('show');
+  c.classList.toggle('open');
+}
+</script>
+</body>
+</html>
+{{define "base-css"}}*,*::before,*::after{box-sizing:border-box;margin:0;padding:0}
+:root{
+  --bg:#1a1a1a;--surface:#262626;--surface-hover:#303030;
+  --border:#333;--text:#e8e8e8;--text-secondary:#999;--text-tertiary:#666;
+  --accent:#D97757;--accent-soft:rgba(217,119,87,.12);
+  --user-bg:#353535;--code-bg:#1e1e1e;--code-header:#2a2a2a;
+  --green:#4ade80;--red:#f87171;--blue:#60a5fa;
+  --topbar-bg:rgba(26,26,26,.82);--hairline:rgba(255,255,255,.04);--subtle:rgba(255,255,255,.02);--subtle-border:rgba(255,255,255,.06);
+  --heading:#fff;--inline-code:#e0a370;--inline-code-bg:rgba(255,255,255,.07);--code-text:#d4d4d4;
+  --avatar-user-bg:#444;--avatar-user:#ccc;--terminal-bg:#111;--scrollbar:#444;--scrollbar-hover:#555;
+  --radius:12px;--radius-sm:8px;--max-w:780px;
+}
+{{if eq .Theme "light"}}:root{ {{- template "light-vars"}}}
+{{else if eq .Theme "auto"}}@media(prefers-color-scheme:light){:root:not([data-theme=dark]){ {{- template "light-vars"}}}}
+:root[data-theme=light]{ {{- template "light-vars"}}}
+{{end}}
+html{font-size:15px;-webkit-font-smoothing:antialiased;-moz-osx-font-smoothing:grayscale}
+body{background:var(--bg);color:var(--text);font-family:'Inter',system-ui,-apple-system,sans-serif;line-height:1.65;min-height:100vh}
+
+.topbar{position:sticky;top:0;z-index:100;background:var(--topbar-bg);backdrop-filter:blur(20px) saturate(1.4);-webkit-backdrop-filter:blur(20px) saturate(1.4);border-bottom:1px solid var(--border)}
+.topbar-inner{max-width:var(--max-w);margin:0 auto;padding:14px 24px;display:flex;align-items:center;justify-content:space-between}
+.topbar-left{display:flex;align-items:center;gap:12px}
+.logo{display:flex;align-items:center;gap:9px;text-decoration:none;color:var(--text)}
+.logo svg{width:28px;height:28px}
+.logo-text{font-weight:600;font-size:.95rem;letter-spacing:-.01em}
+.theme-toggle{background:none;border:1px solid var(--border);border-radius:var(--radius-sm);color:var(--text-secondary);width:32px;height:32px;display:flex;align-items:center;justify-content:center;cursor:pointer}
+.theme-toggle:hover{background:var(--surface-hover);color:var(--text)}
+.theme-toggle svg{width:16px;height:16px}
+.logo-badge{font-size:.65rem;font-weight:600;letter-spacing:.04em;text-transform:uppercase;color:var(--accent);background:var(--accent-soft);padding:2px 7px;border-radius:5px;margin-left:2px}
+
+.footer{text-align:center;padding:40px 24px 32px;font-size:.72rem;color:var(--text-tertiary)}
+.footer a{color:var(--accent);text-decoration:none}
+.footer a:hover{text-decoration:underline}
+
+::-webkit-scrollbar{width:6px;height:6px}
+::-webkit-scrollbar-track{background:transparent}
+::-webkit-scrollbar-thumb{background:var(--scrollbar);border-radius:3px}
+::-webkit-scrollbar-thumb:hover{background:var(--scrollbar-hover)}
+{{end}}
+{{define "topbar"}}<nav class="topbar">
+  <div class="topbar-inner">
+    <div class="topbar-left">
+      <span class="logo">
+        <svg viewBox="0 0 24 24" fill="#D97757" width="28" height="28"><path d="M17.3041 3.541h-3.6718l6.696 16.918H24Zm-10.6082 0L0 20.459h3.7442l1.3693-3.5527h7.0052l1.3693 3.5528h3.7442L10.5363 3.5409Zm-.3712 10.2232 2.2914-5.9456 2.2914 5.9456Z"/></svg>
+        <span class="logo-text">Claude Code</span>
+        <span class="logo-badge">Share</span>
+      </span>
+    </div>
+    {{if eq .Theme "auto"}}<button class="theme-toggle" onclick="toggleTheme()" aria-label="Toggle light/dark theme" title="Toggle light/dark theme">
+      <svg viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="1.5"><circle cx="8" cy="8" r="5.5"/><path d="M8 2.5v11a5.5 5.5 0 0 0 0-11z" fill="currentColor"/></svg>
+    </button>{{end}}
+  </div>
+</nav>{{end}}
+{{define "favicon"}}<link rel="icon" type="image/svg+xml" href="data:image/svg+xml,<svg xmlns='http://www.w3.org/2000/svg' viewBox='0 0 16 16'><path fill='%23D97757' d='m3.127 10.604 3.135-1.76.053-.153-.053-.085H6.11l-.525-.032-1.791-.048-1.554-.065-1.505-.08-.38-.081L0 7.832l.036-.234.32-.214.455.04 1.009.069 1.513.105 1.097.064 1.626.17h.259l.036-.105-.089-.065-.068-.064-1.566-1.062-1.695-1.121-.887-.646-.48-.327-.243-.306-.104-.67.435-.48.585.04.15.04.593.456 1.267.981 1.654 1.218.242.202.097-.068.012-.049-.109-.181-.9-1.626-.96-1.655-.428-.686-.113-.411a2 2 0 0 1-.068-.484l.496-.674L4.446 0l.662.089.279.242.411.94.666 1.48 1.033 2.014.302.597.162.553.06.17h.105v-.097l.085-1.134.157-1.392.154-1.792.052-.504.25-.605.497-.327.387.186.319.456-.045.294-.19 1.23-.37 1.93-.243 1.29h.142l.161-.16.654-.868 1.097-1.372.484-.545.565-.601.363-.287h.686l.505.751-.226.775-.707.895-.585.759-.839 1.13-.524.904.048.072.125-.012 1.897-.403 1.024-.186 1.223-.21.553.258.06.263-.218.536-1.307.323-1.533.307-2.284.54-.028.02.032.04 1.029.098.44.024h1.077l2.005.15.525.346.315.424-.053.323-.807.411-3.631-.863-.872-.218h-.12v.073l.726.71 1.331 1.202 1.667 1.55.084.383-.214.302-.226-.032-1.464-1.101-.565-.497-1.28-1.077h-.084v.113l.295.432 1.557 2.34.08.718-.112.234-.404.141-.444-.08-.911-1.28-.94-1.44-.759-1.291-.093.053-.448 4.821-.21.246-.484.186-.403-.307-.214-.496.214-.98.258-1.28.21-1.016.19-1.263.112-.42-.008-.028-.092.012-.953 1.307-1.448 1.957-1.146 1.227-.274.109-.477-.247.045-.44.266-.39 1.586-2.018.956-1.25.617-.723-.004-.105h-.036l-4.212 2.736-.75.096-.324-.302.04-.496.154-.162 1.267-.871z'/></svg>">{{end}}
+{{define "footer"}}<div class="footer">
+  Shared from Claude Code · Generated by Claude, an AI assistant by <a href="https://anthropic.com" target="_blank">Anthropic</a>
+</div>{{end}}
+{{define "theme-script"}}{{if eq .Theme "auto"}}(function(){try{var t=localStorage.getItem('claude-share-theme');if(t)document.documentElement.dataset.theme=t}catch(e){}})();
+function toggleTheme(){
+  var r=document.documentElement;
+  var cur=r.dataset.theme||(matchMedia('(prefers-color-scheme: light)').matches?'light':'dark');
+  r.dataset.theme=cur==='light'?'dark':'light';
+  try{localStorage.setItem('claude-share-theme',r.dataset.theme)}catch(e){}
+}
+{{end}}{{end}}
+{{define "light-vars"}}
+  --bg:#faf9f5;--surface:#f0eee6;--surface-hover:#e8e6dc;
+  --border:#e0ddd2;--text:#1f1e1d;--text-secondary:#5e5d59;--text-tertiary:#8f8d86;
+  --accent:#c6613f;--accent-soft:rgba(217,119,87,.14);
+  --user-bg:#efece3;--code-bg:#f5f4ef;--code-header:#ebe9e1;
+  --green:#16a34a;--red:#dc2626;--blue:#2563eb;
+  --topbar-bg:rgba(250,249,245,.85);--hairline:rgba(0,0,0,.06);--subtle:rgba(0,0,0,.02);--subtle-border:rgba(0,0,0,.08);
+  --heading:#000;--inline-code:#b4532f;--inline-code-bg:rgba(0,0,0,.06);--code-text:#2b2b2b;
+  --avatar-user-bg:#dcd9cf;--avatar-user:#444;--terminal-bg:#f0eee6;--scrollbar:#ccc;--scrollbar-hover:#aaa;
+{{end}}
+{{define "images"}}{{range .}}{{if .Src}}<img class="msg-image" src="{{.Src}}" alt="Image" loading="lazy">{{else}}<div class="image-omitted">Image omitted{{with .MediaType}} ({{.}}){{end}}</div>{{end}}{{end}}{{end}}
+
+{{define "msg-time"}}{{if .Time}}<span class="msg-time">{{with .Gap}}<span class="msg-gap" title="Time since the previous message">{{.}}</span>{{end}}<time datetime="{{.DateTime}}" title="{{.DateTime}}">{{.Time}}</time></span>{{end}}{{end}}
+
+{{define "tool-result"}}<div class="tool-block">
+  <div class="tool-header" onclick="toggleTool(this)">
+    <svg class="tool-icon" viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="1.5"><path d="M3 2h7l3 3v9H3z"/><path d="M10 2v3h3"/></svg>
+    <span class="tool-name">{{with .ToolName}}{{.}} {{end}}{{if .IsError}}Error{{else}}Result{{end}}</span>
+    <span class="tool-status"><span class="dot {{if .IsError}}error{{else}}success{{end}}"></span></span>
+    <svg class="tool-chevron" viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="2"><path d="M4 6l4 4 4-4"/></svg>
+  </div>
+  <div class="tool-body">{{.HTML}}{{template "images" .Images}}</div>
+</div>{{end}}
+
+{{define "messages"}}
+{{range .}}
+  {{if eq .Role "fork"}}
+  <div class="branch-group">
+    <div class="branch-switcher">
+      <svg viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="1.5"><circle cx="4" cy="3" r="1.5"/><circle cx="4" cy="13" r="1.5"/><circle cx="12" cy="5" r="1.5"/><path d="M4 4.5v7M12 6.5c0 3-8 2-8 5"/></svg>
+      <span class="branch-label">Current branch</span>
+      <button class="branch-btn" onclick="switchBranch(this,-1)" aria-label="Previous branch">‹</button>
+      <span class="branch-pos">{{len .Branches}} / {{len .Branches}}</span>
+      <button class="branch-btn" onclick="switchBranch(this,1)" aria-label="Next branch">›</button>
+    </div>
+    {{$last := len .Branches}}
+    {{range $i, $b := .Branches}}
+    <div class="branch{{if eq (inc $i) $last}} active{{end}}">{{template "messages" $b}}</div>
+    {{end}}
+  </div>
+  {{else if eq .Role "user"}}
   <div class="msg msg-user">
     <div class="msg-header">
       <div class="avatar avatar-user">U</div>
-      <span class="msg-sender">You</span>
+      <span class="msg-sender">{{if .Subagent}}Task prompt{{else}}You{{end}}</span>
+      {{template "msg-time" .}}
     </div>
     <div class="msg-body">
       {{range .Blocks}}
-        {{if eq .Type "text"}}{{.HTML}}{{end}}
+        {{if eq .Type "text"}}{{.HTML}}{{else if eq .Type "image"}}{{template "images" .Images}}{{else if and (eq .Type "tool_result") .ToolName}}{{template "tool-result" .}}{{end}}
       {{end}}
     </div>
   </div>
@@ -359,12 +1173,16 @@ body{background:var(--bg);color:var(--text);font-family:'Inter',system-ui,-apple
       <div class="avatar avatar-assistant">
         <svg viewBox="0 0 16 16" fill="currentColor"><path d="m3.127 10.604 3.135-1.76.053-.153-.053-.085H6.11l-.525-.032-1.791-.048-1.554-.065-1.505-.08-.38-.081L0 7.832l.036-.234.32-.214.455.04 1.009.069 1.513.105 1.097.064 1.626.17h.259l.036-.105-.089-.065-.068-.064-1.566-1.062-1.695-1.121-.887-.646-.48-.327-.243-.306-.104-.67.435-.48.585.04.15.04.593.456 1.267.981 1.654 1.218.242.202.097-.068.012-.049-.109-.181-.9-1.626-.96-1.655-.428-.686-.113-.411a2 2 0 0 1-.068-.484l.496-.674L4.446 0l.662.089.279.242.411.94.666 1.48 1.033 2.014.302.597.162.553.06.17h.105v-.097l.085-1.134.157-1.392.154-1.792.052-.504.25-.605.497-.327.387.186.319.456-.045.294-.19 1.23-.37 1.93-.243 1.29h.142l.161-.16.654-.868 1.097-1.372.484-.545.565-.601.363-.287h.686l.505.751-.226.775-.707.895-.585.759-.839 1.13-.524.904.048.072.125-.012 1.897-.403 1.024-.186 1.223-.21.553.258.06.263-.218.536-1.307.323-1.533.307-2.284.54-.028.02.032.04 1.029.098.44.024h1.077l2.005.15.525.346.315.424-.053.323-.807.411-3.631-.863-.872-.218h-.12v.073l.726.71 1.331 1.202 1.667 1.55.084.383-.214.302-.226-.032-1.464-1.101-.565-.497-1.28-1.077h-.084v.113l.295.432 1.557 2.34.08.718-.112.234-.404.141-.444-.08-.911-1.28-.94-1.44-.759-1.291-.093.053-.448 4.821-.21.246-.484.186-.403-.307-.214-.496.214-.98.258-1.28.21-1.016.19-1.263.112-.42-.008-.028-.092.012-.953 1.307-1.448 1.957-1.146 1.227-.274.109-.477-.247.045-.44.266-.39 1.586-2.018.956-1.25.617-.723-.004-.105h-.036l-4.212 2.736-.75.096-.324-.302.04-.496.154-.162 1.267-.871z"/></svg>
       </div>
-      <span class="msg-sender">Claude</span>
+      <span class="msg-sender">{{if .Subagent}}Subagent{{else}}Claude{{end}}</span>
+      {{if .Model}}<span class="model-badge" title="Model changed">{{.Model}}</span>{{end}}
+      {{template "msg-time" .}}
     </div>
     <div class="msg-body">
       {{range .Blocks}}
         {{if eq .Type "text"}}
           {{.HTML}}
+        {{else if eq .Type "image"}}
+          {{template "images" .Images}}
         {{else if eq .Type "thinking"}}
           <div class="thinking-block">
             <div class="thinking-header" onclick="toggleThinking(this)">
@@ -379,46 +1197,35 @@ body{background:var(--bg);color:var(--text);font-family:'Inter',system-ui,-apple
             <div class="tool-header" onclick="toggleTool(this)">
               <svg class="tool-icon" viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="1.5"><path d="M4 4l4 4-4 4"/><path d="M10 12h4"/></svg>
               <span class="tool-name">{{.ToolName}}</span>
-              <span class="tool-status"><span class="dot success"></span></span>
+              {{if .Summary}}<span class="tool-summary">{{.Summary}}</span>{{end}}
+              {{if .HasResult}}<span class="tool-status"><span class="dot {{if .IsError}}error{{else}}success{{end}}"></span>{{if .IsError}}Error{{end}}</span>{{else}}<span class="tool-status" title="No result recorded"><span class="dot pending"></span></span>{{end}}
               <svg class="tool-chevron" viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="2"><path d="M4 6l4 4 4-4"/></svg>
             </div>
-            <div class="tool-body">{{.HTML}}</div>
-          </div>
-        {{else if eq .Type "tool_result"}}
-          <div class="tool-block">
-            <div class="tool-header" onclick="toggleTool(this)">
-              <svg class="tool-icon" viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="1.5"><path d="M3 2h7l3 3v9H3z"/><path d="M10 2v3h3"/></svg>
-              <span class="tool-name">{{if .IsError}}Error{{else}}Result{{end}}</span>
-              <span class="tool-status"><span class="dot {{if .IsError}}error{{else}}success{{end}}"></span></span>
-              <svg class="tool-chevron" viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="2"><path d="M4 6l4 4 4-4"/></svg>
+            <div class="tool-body">
+              {{if .Custom}}{{.HTML}}{{else}}
+              {{if .HasResult}}<div class="tool-section">Input</div>{{end}}
+              {{.HTML}}
+              {{if .HasResult}}<div class="tool-section">{{if .IsError}}Error{{else}}Output{{end}}</div>{{.ResultHTML}}{{end}}
+              {{end}}
+              {{template "images" .Images}}
             </div>
-            <div class="tool-body">{{.HTML}}</div>
+            {{if .Sidechain}}
+            <div class="subagent">
+              <div class="subagent-header" onclick="toggleTool(this)">
+                <svg class="tool-icon" viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="1.5"><circle cx="8" cy="5" r="2.5"/><path d="M3 14c0-2.8 2.2-5 5-5s5 2.2 5 5"/></svg>
+                <span>Subagent conversation · {{len .Sidechain}} messages</span>
+                <svg class="tool-chevron" viewBox="0 0 16 16" fill="none" stroke="currentColor" stroke-width="2"><path d="M4 6l4 4 4-4"/></svg>
+              </div>
+              <div class="subagent-body">{{template "messages" .Sidechain}}</div>
+            </div>
+            {{end}}
           </div>
+        {{else if eq .Type "tool_result"}}
+          {{template "tool-result" .}}
         {{end}}
       {{end}}
     </div>
   </div>
   {{end}}
 {{end}}
-</div>
-
-<div class="footer">
-  Shared from Claude Code · Generated by Claude, an AI assistant by <a href="https://anthropic.com" target="_blank">Anthropic</a>
-</div>
-
-<script>
-function toggleTool(el){
-  var b=el.nextElementSibling;
-  var c=el.querySelector('.tool-chevron');
-  b.classList.toggle('show');
-  c.classList.toggle('open');
-}
-function toggleThinking(el){
-  var b=el.nextElementSibling;
-  var c=el.querySelector('.tool-chevron');
-  b.classList.toggle('show');
-  c.classList.toggle('open');
-}
-</script>
-</body>
-</html>`
+{{end}}`
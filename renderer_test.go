@@ -3,6 +3,7 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,6 +40,28 @@ func TestRenderHTML_BasicConversation(t *testing.T) {
 	assert.Contains(t, html, "Jan 1, 2025")
 }
 
+func TestRenderHTML_UsagePanel(t *testing.T) {
+	messages := []Message{userMsg("Hello"), assistantMsg("Hi there")}
+	usage := &UsageReport{
+		Models: []ModelUsage{
+			{Model: "claude-opus-4-5", Messages: 1, Usage: Usage{InputTokens: 1500, OutputTokens: 20}, Cost: 0.01, Priced: true},
+			{Model: "claude-haiku-4-5", Messages: 1, Usage: Usage{InputTokens: 10}, Cost: 0.001, Priced: true},
+		},
+		Total: ModelUsage{Messages: 2, Usage: Usage{InputTokens: 1510, OutputTokens: 20}, Cost: 0.011, Priced: true},
+	}
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.NotContains(t, html, `<details class="usage-panel">`)
+
+	html, err = RenderHTML(messages, stubMeta, RenderOpts{Usage: usage})
+	require.NoError(t, err)
+	assert.Contains(t, html, `<details class="usage-panel">`)
+	assert.Contains(t, html, "1.5K input · 20 output")
+	assert.Contains(t, html, "claude-opus-4-5")
+	assert.Contains(t, html, `<tr class="usage-total">`)
+}
+
 func TestRenderHTML_SkipsUserToolResultMessages(t *testing.T) {
 	messages := []Message{
 		userMsg("Do something"),
@@ -51,6 +74,18 @@ func TestRenderHTML_SkipsUserToolResultMessages(t *testing.T) {
 	assert.Equal(t, 2, strings.Count(html, "class=\"msg "))
 }
 
+func TestRenderHTML_ShowsBranchResultForSharedCall(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Blocks: []ContentBlock{{Type: "tool_result", ToolName: "Bash", Text: "first run"}}},
+	}
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{IncludeTools: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, countClass(html, "msg-user"))
+	assert.Contains(t, html, "Bash Result")
+	assert.Contains(t, html, "first run")
+}
+
 func TestRenderHTML_IncludesToolUse(t *testing.T) {
 	messages := []Message{
 		{Role: "assistant", Blocks: []ContentBlock{
@@ -65,6 +100,25 @@ func TestRenderHTML_IncludesToolUse(t *testing.T) {
 	assert.Contains(t, html, "Read")
 }
 
+func TestRenderHTML_ToolCallShowsPairedResult(t *testing.T) {
+	messages := []Message{
+		{Role: "assistant", Blocks: []ContentBlock{
+			{Type: "tool_use", ToolName: "Bash", ToolInput: `{"command":"ls"}`, Result: &ContentBlock{Type: "tool_result", Text: "main.go"}},
+			{Type: "tool_use", ToolName: "Bash", ToolInput: `{"command":"false"}`, Result: &ContentBlock{Type: "tool_result", Text: "boom", IsError: true}},
+			{Type: "tool_use", ToolName: "Read", ToolInput: `{}`},
+		}},
+	}
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{IncludeTools: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(html, `<div class="tool-block">`))
+	assert.Contains(t, html, "main.go")
+	assert.Contains(t, html, "boom")
+	assert.Equal(t, 1, strings.Count(html, `class="dot success"`))
+	assert.Equal(t, 1, strings.Count(html, `class="dot error"`))
+	assert.Equal(t, 1, strings.Count(html, `class="dot pending"`))
+}
+
 func TestRenderHTML_IncludesThinking(t *testing.T) {
 	messages := []Message{
 		{Role: "assistant", Blocks: []ContentBlock{
@@ -79,6 +133,41 @@ func TestRenderHTML_IncludesThinking(t *testing.T) {
 	assert.Contains(t, html, "Thinking")
 }
 
+func TestRenderHTML_NestsSidechainUnderToolUse(t *testing.T) {
+	messages := []Message{
+		{Role: "assistant", Blocks: []ContentBlock{
+			{Type: "tool_use", ToolName: "Task", ToolInput: `{"prompt":"Look around"}`, Sidechain: []Message{
+				userMsg("Look around"),
+				assistantMsg("Nothing suspicious"),
+			}},
+		}},
+	}
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{IncludeTools: true})
+	require.NoError(t, err)
+	assert.Contains(t, html, "Subagent conversation · 2 messages")
+	assert.Contains(t, html, "Nothing suspicious")
+	assert.Contains(t, html, "Task prompt")
+	assert.Equal(t, 1, countClass(html, "msg-user"))
+	assert.Equal(t, 2, countClass(html, "msg-assistant"))
+}
+
+func TestRenderHTML_BranchSwitcher(t *testing.T) {
+	edited := userMsg("Edited question")
+	edited.Branches = [][]Message{{userMsg("Original question"), assistantMsg("Original answer")}}
+	messages := []Message{userMsg("Start"), assistantMsg("Ok"), edited, assistantMsg("Edited answer")}
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(html, `class="branch-group"`))
+	assert.Contains(t, html, "2 / 2")
+	assert.Contains(t, html, "Original answer")
+	assert.Contains(t, html, "Edited answer")
+	assert.Equal(t, 1, strings.Count(html, `class="branch active"`))
+	assert.Less(t, strings.Index(html, "Original answer"), strings.Index(html, `class="branch active"`))
+	assert.Equal(t, 3, countClass(html, "msg-user"))
+}
+
 func TestRenderHTML_MetaFields(t *testing.T) {
 	meta := SessionMeta{
 		SessionID:    "abc-123",
@@ -96,6 +185,107 @@ func TestRenderHTML_MetaFields(t *testing.T) {
 	assert.Contains(t, html, "Fix the bug")
 }
 
+func TestRenderHTML_SessionDetails(t *testing.T) {
+	meta := SessionMeta{SessionID: "abc-123", SessionDetails: SessionDetails{
+		Cwd:       "/home/user/webapp",
+		GitBranch: "feature/login",
+		Version:   "1.0.43",
+		Models:    []string{"claude-sonnet-4-5", "claude-opus-4-5"},
+	}}
+
+	html, err := RenderHTML([]Message{userMsg("hi")}, meta, RenderOpts{})
+	require.NoError(t, err)
+	assert.Contains(t, html, "claude-sonnet-4-5, claude-opus-4-5")
+	assert.Contains(t, html, "feature/login")
+	assert.Contains(t, html, "/home/user/webapp")
+	assert.Contains(t, html, "Claude Code 1.0.43")
+}
+
+func TestRenderHTML_ModelBadgeOnlyWhenModelChanges(t *testing.T) {
+	reply := func(model, text string) Message {
+		m := assistantMsg(text)
+		m.Model = model
+		return m
+	}
+	messages := []Message{
+		userMsg("q1"), reply("claude-sonnet-4-5", "a1"),
+		userMsg("q2"), reply("claude-sonnet-4-5", "a2"),
+		userMsg("q3"), reply(syntheticModel, "API Error"),
+		userMsg("q4"), reply("claude-opus-4-5", "a4"),
+	}
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(html, `class="model-badge"`))
+	assert.Contains(t, html, `<span class="model-badge" title="Model changed">claude-opus-4-5</span>`)
+}
+
+func TestRenderHTML_ModelBadgeIgnoresHiddenMessages(t *testing.T) {
+	sonnet := assistantMsg("a1")
+	sonnet.Model = "claude-sonnet-4-5"
+	hidden := Message{Role: "assistant", Model: "claude-opus-4-5"}
+	opus := assistantMsg("a2")
+	opus.Model = "claude-opus-4-5"
+	messages := []Message{userMsg("q1"), sonnet, userMsg("q2"), hidden, opus}
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.Contains(t, html, `<span class="model-badge" title="Model changed">claude-opus-4-5</span>`)
+}
+
+func TestRenderHTML_MessageTimesAndGaps(t *testing.T) {
+	at := func(m Message, ts string) Message {
+		m.Timestamp = ts
+		return m
+	}
+	messages := []Message{
+		at(userMsg("q1"), "2025-03-03T22:50:00Z"),
+		at(assistantMsg("a1"), "2025-03-03T22:50:20Z"),
+		at(userMsg("q2"), "2025-03-03T22:54:32Z"),
+		at(assistantMsg("a2"), "2025-03-04T00:10:00.5Z"),
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{Location: berlin})
+	require.NoError(t, err)
+	assert.Contains(t, html, `<time datetime="2025-03-03T23:50:00&#43;01:00" title="2025-03-03T23:50:00&#43;01:00">23:50</time>`)
+	assert.Contains(t, html, ">Mar 4, 01:10</time>", "a new day shows the date")
+	assert.Equal(t, 2, strings.Count(html, `class="msg-gap"`), "pauses under a minute aren't marked")
+	assert.Contains(t, html, ">&#43;4m 12s</span>")
+	assert.Contains(t, html, ">&#43;1h 15m</span>")
+	assert.Contains(t, html, `title="Session duration"`)
+	assert.Contains(t, html, "1h 20m")
+}
+
+func TestRenderHTML_NoTimesWithoutTimestamps(t *testing.T) {
+	html, err := RenderHTML([]Message{userMsg("hi"), assistantMsg("hello")}, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.NotContains(t, html, "<time ")
+	assert.NotContains(t, html, `title="Session duration"`)
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "9s", formatDuration(9*time.Second))
+	assert.Equal(t, "4m 12s", formatDuration(4*time.Minute+12*time.Second))
+	assert.Equal(t, "1h 0m", formatDuration(time.Hour+29*time.Second))
+	assert.Equal(t, "26h 3m", formatDuration(26*time.Hour+3*time.Minute))
+}
+
+func TestRenderHTML_EmbedsImages(t *testing.T) {
+	pasted := userMsg("What is wrong here?")
+	pasted.Blocks = append(pasted.Blocks, ContentBlock{Type: "image", MediaType: "image/png", Data: "iVBORw0KGgo="})
+	call := ContentBlock{Type: "tool_use", ToolName: "Screenshot", ToolInput: `{}`, ToolUseID: "t1",
+		Result: &ContentBlock{Type: "tool_result", Images: []ContentBlock{{Type: "image", MediaType: "image/jpeg"}}}}
+	messages := []Message{pasted, {Role: "assistant", Blocks: []ContentBlock{call}}}
+
+	html, err := RenderHTML(messages, stubMeta, RenderOpts{IncludeTools: true})
+	require.NoError(t, err)
+	assert.Contains(t, html, `<img class="msg-image" src="data:image/png;base64,iVBORw0KGgo=" alt="Image" loading="lazy">`)
+	assert.Contains(t, html, `<div class="image-omitted">Image omitted (image/jpeg)</div>`)
+	assert.NotContains(t, html, "ZgotmplZ")
+}
+
 func TestRenderHTML_FallbackTitle(t *testing.T) {
 	html, err := RenderHTML([]Message{userMsg("hi")}, SessionMeta{SessionID: "t", FirstPrompt: ""}, RenderOpts{})
 	require.NoError(t, err)
@@ -131,7 +321,9 @@ func TestRenderMarkdown_BasicText(t *testing.T) {
 }
 
 func TestRenderMarkdown_CodeBlock(t *testing.T) {
-	assert.Contains(t, renderMarkdown("```go\nfmt.Println(\"hi\")\n```"), "style=")
+	result := renderMarkdown("```go\nfmt.Println(\"hi\")\n```")
+	assert.Contains(t, result, `class="chroma"`)
+	assert.Contains(t, result, `<span class="nf">Println</span>`)
 }
 
 func TestRenderMarkdown_InlineCode(t *testing.T) {
@@ -164,7 +356,8 @@ func TestHighlightCode_ValidLanguage(t *testing.T) {
 	result, err := highlightCode("x := 1", "go")
 	require.NoError(t, err)
 	assert.Contains(t, result, "<pre")
-	assert.Contains(t, result, "style=")
+	assert.Contains(t, result, `class="chroma"`)
+	assert.Contains(t, result, `<span class="o">:=</span>`)
 }
 
 func TestHighlightCode_UnknownLanguage(t *testing.T) {
@@ -184,3 +377,135 @@ func TestHighlightJSON_InvalidJSON(t *testing.T) {
 	assert.Contains(t, result, "not")
 	assert.Contains(t, result, "json")
 }
+
+func TestRenderToolView_EditShowsDiff(t *testing.T) {
+	view, ok := renderToolView(ContentBlock{
+		ToolName:  "Edit",
+		ToolInput: `{"file_path":"/src/main.go","old_string":"x := 1","new_string":"x := 2"}`,
+	})
+	require.True(t, ok)
+	assert.Equal(t, "/src/main.go", view.Summary)
+	assert.Contains(t, view.Body, "-x := 1")
+	assert.Contains(t, view.Body, "+x := 2")
+}
+
+func TestRenderToolView_MultiEditShowsEachDiff(t *testing.T) {
+	view, ok := renderToolView(ContentBlock{
+		ToolName:  "MultiEdit",
+		ToolInput: `{"file_path":"a.txt","edits":[{"old_string":"one","new_string":"uno"},{"old_string":"two","new_string":"dos"}]}`,
+	})
+	require.True(t, ok)
+	assert.Equal(t, "a.txt (2 edits)", view.Summary)
+	assert.Contains(t, view.Body, "+uno")
+	assert.Contains(t, view.Body, "+dos")
+}
+
+func TestRenderToolView_BashShowsCommandAndOutput(t *testing.T) {
+	view, ok := renderToolView(ContentBlock{
+		ToolName:  "Bash",
+		ToolInput: `{"command":"go test ./...","description":"Run tests"}`,
+		Result:    &ContentBlock{Text: "FAIL <pkg>", IsError: true},
+	})
+	require.True(t, ok)
+	assert.Equal(t, "Run tests", view.Summary)
+	assert.Contains(t, view.Body, "go test ./...")
+	assert.Contains(t, view.Body, `class="terminal-output error"`)
+	assert.Contains(t, view.Body, "FAIL &lt;pkg&gt;")
+}
+
+func TestRenderToolView_ReadStripsLineNumbers(t *testing.T) {
+	view, ok := renderToolView(ContentBlock{
+		ToolName:  "Read",
+		ToolInput: `{"file_path":"/src/main.go"}`,
+		Result:    &ContentBlock{Text: "    10→package main\n    11→\n    12→func main() {}\n<system-reminder>ignored</system-reminder>"},
+	})
+	require.True(t, ok)
+	assert.Contains(t, view.Body, "/src/main.go")
+	assert.Contains(t, view.Body, "package")
+	assert.Contains(t, view.Body, ">10<")
+	assert.NotContains(t, view.Body, "→")
+	assert.NotContains(t, view.Body, "ignored")
+}
+
+func TestRenderToolView_WriteHighlightsByExtension(t *testing.T) {
+	view, ok := renderToolView(ContentBlock{
+		ToolName:  "Write",
+		ToolInput: `{"file_path":"hello.py","content":"print('hi')\n"}`,
+	})
+	require.True(t, ok)
+	assert.Contains(t, view.Body, "hello.py")
+	assert.Contains(t, view.Body, `<span class="nb">print</span>`)
+}
+
+func TestRenderToolView_TodoWriteChecklist(t *testing.T) {
+	view, ok := renderToolView(ContentBlock{
+		ToolName:  "TodoWrite",
+		ToolInput: `{"todos":[{"content":"Write parser","status":"completed"},{"content":"Write renderer","status":"in_progress"},{"content":"Ship","status":"pending"}]}`,
+	})
+	require.True(t, ok)
+	assert.Equal(t, "1/3 done", view.Summary)
+	assert.Contains(t, view.Body, `class="todo todo-completed"`)
+	assert.Contains(t, view.Body, `class="todo todo-in_progress"`)
+	assert.Contains(t, view.Body, "Ship")
+}
+
+func TestToolSummary(t *testing.T) {
+	assert.Equal(t, "/src/main.go", toolSummary(ContentBlock{ToolName: "Edit", ToolInput: `{"file_path":"/src/main.go","old_string":"a","new_string":"b"}`}))
+	assert.Equal(t, "TODO in src (*.go)", toolSummary(ContentBlock{ToolName: "Grep", ToolInput: `{"pattern":"TODO","path":"src","glob":"*.go"}`}))
+	assert.Equal(t, "go test ./... …", toolSummary(ContentBlock{ToolName: "Bash", ToolInput: `{"command":"go test ./...\ngo vet ./..."}`}))
+	assert.Equal(t, "1/2 done", toolSummary(ContentBlock{ToolName: "TodoWrite", ToolInput: `{"todos":[{"status":"completed"},{"status":"pending"}]}`}))
+	assert.Empty(t, toolSummary(ContentBlock{ToolName: "WebFetch", ToolInput: `{"url":"https://example.com"}`}))
+	assert.Empty(t, toolSummary(ContentBlock{ToolName: "Bash", ToolInput: `not json`}))
+}
+
+func TestRenderToolView_UnknownToolFallsBack(t *testing.T) {
+	_, ok := renderToolView(ContentBlock{ToolName: "WebFetch", ToolInput: `{"url":"https://example.com"}`})
+	assert.False(t, ok)
+}
+
+func TestRenderToolView_UnexpectedInputFallsBack(t *testing.T) {
+	_, ok := renderToolView(ContentBlock{ToolName: "Edit", ToolInput: `{"path":"missing file_path"}`})
+	assert.False(t, ok)
+
+	_, ok = renderToolView(ContentBlock{ToolName: "Bash", ToolInput: `not json`})
+	assert.False(t, ok)
+}
+
+func TestRenderHTML_DefaultsToDarkTheme(t *testing.T) {
+	html, err := RenderHTML([]Message{userMsg("hi")}, stubMeta, RenderOpts{})
+	require.NoError(t, err)
+	assert.Contains(t, html, "--bg:#1a1a1a")
+	assert.NotContains(t, html, "--bg:#faf9f5")
+	assert.NotContains(t, html, "theme-toggle\"")
+	assert.Contains(t, html, ".chroma .k {")
+}
+
+func TestRenderHTML_LightTheme(t *testing.T) {
+	html, err := RenderHTML([]Message{userMsg("hi")}, stubMeta, RenderOpts{Theme: "light"})
+	require.NoError(t, err)
+	assert.Contains(t, html, "--bg:#faf9f5")
+	assert.NotContains(t, html, "prefers-color-scheme")
+	assert.Contains(t, html, ".chroma .k { color: #cf222e }")
+}
+
+func TestRenderHTML_AutoThemeHasToggleAndScopedStyles(t *testing.T) {
+	html, err := RenderHTML([]Message{userMsg("hi")}, stubMeta, RenderOpts{Theme: "auto"})
+	require.NoError(t, err)
+	assert.Contains(t, html, "@media(prefers-color-scheme:light)")
+	assert.Contains(t, html, ":root[data-theme=light] .chroma .k { color: #cf222e }")
+	assert.Contains(t, html, ":root:not([data-theme=dark]) .chroma .k { color: #cf222e }")
+	assert.Contains(t, html, `onclick="toggleTheme()"`)
+}
+
+func TestValidTheme(t *testing.T) {
+	for _, theme := range []string{"dark", "light", "auto"} {
+		assert.True(t, validTheme(theme), theme)
+	}
+	assert.False(t, validTheme("sepia"))
+	assert.False(t, validTheme(""))
+}
+
+func TestRenderHTML_UnknownTheme(t *testing.T) {
+	_, err := RenderHTML(nil, stubMeta, RenderOpts{Theme: "sepia"})
+	assert.ErrorContains(t, err, "unknown theme")
+}
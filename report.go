@@ -0,0 +1,578 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ReportOpts selects the sessions a usage report covers and how they are
+// grouped.
+type ReportOpts struct {
+	Filter listFilter
+	Period string // "day" or "week"
+	Prices PriceTable
+	Jobs   int
+}
+
+// ReportRow totals the sessions of one project, period or model.
+type ReportRow struct {
+	Key      string  `json:"key"`
+	Sessions int     `json:"sessions"`
+	Messages int     `json:"messages"`
+	Usage    Usage   `json:"usage"`
+	Cost     float64 `json:"cost_usd"`
+	Priced   bool    `json:"priced"`
+}
+
+func (r *ReportRow) add(m ModelUsage) {
+	r.Messages += m.Messages
+	r.Usage.Add(m.Usage)
+	r.Cost += m.Cost
+	r.Priced = r.Priced && m.Priced
+}
+
+// ToolStats counts the calls of one tool and how many of them returned an
+// error.
+type ToolStats struct {
+	Name      string  `json:"name"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"` // Errors / Calls
+}
+
+// Report aggregates token usage and tool calls across sessions. Projects and
+// models are ordered by cost, periods chronologically and tools by number of
+// calls.
+type Report struct {
+	Period   string      `json:"period"`
+	Total    ReportRow   `json:"total"`
+	Projects []ReportRow `json:"projects"`
+	Periods  []ReportRow `json:"periods"`
+	Models   []ReportRow `json:"models"`
+	Tools    []ToolStats `json:"tools"`
+	Skipped  []string    `json:"skipped,omitempty"` // "<id>: <reason>"
+}
+
+// BuildReport reads every session matching opts.Filter, up to opts.Jobs at a
+// time. Sessions are counted in the period they started in. Sessions that
+// can't be read are listed in Skipped.
+func BuildReport(claudeDirs []string, opts ReportOpts) (Report, error) {
+	report := Report{Period: opts.Period, Total: ReportRow{Key: "Total", Priced: true}}
+
+	sessions, err := ListSessions(claudeDirs)
+	if err != nil {
+		return report, err
+	}
+	paths, err := sessionFiles(claudeDirs)
+	if err != nil {
+		return report, err
+	}
+	var matched []SessionSummary
+	for _, s := range sessions {
+		if opts.Filter.match(s) {
+			matched = append(matched, s)
+		}
+	}
+
+	type sessionResult struct {
+		usage UsageReport
+		tools map[string]ToolStats
+		err   error
+	}
+	results := make([]sessionResult, len(matched))
+	forEachParallel(len(matched), opts.Jobs, func(i int) {
+		path, ok := paths[matched[i].ID]
+		if !ok {
+			results[i].err = fmt.Errorf("session file not found")
+			return
+		}
+		tail := newSessionTail(path)
+		if _, err := tail.Poll(); err != nil {
+			results[i].err = err
+			return
+		}
+		results[i] = sessionResult{usage: tail.Usage(opts.Prices), tools: tail.Tools()}
+	})
+
+	projects := make(map[string]*ReportRow)
+	periods := make(map[string]*ReportRow)
+	models := make(map[string]*ReportRow)
+	tools := make(map[string]*ToolStats)
+	var first, last int64
+	for i, s := range matched {
+		res := results[i]
+		if res.err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", s.ID, res.err))
+			continue
+		}
+		if s.Timestamp > 0 {
+			if first == 0 || s.Timestamp < first {
+				first = s.Timestamp
+			}
+			last = max(last, s.Timestamp)
+		}
+		for _, row := range []*ReportRow{
+			&report.Total,
+			reportRow(projects, s.Project),
+			reportRow(periods, periodKey(s.Timestamp, opts.Period)),
+		} {
+			row.Sessions++
+			row.add(res.usage.Total)
+		}
+		for _, m := range res.usage.Models {
+			row := reportRow(models, m.Model)
+			row.Sessions++
+			row.add(m)
+		}
+		for name, t := range res.tools {
+			total, ok := tools[name]
+			if !ok {
+				total = &ToolStats{Name: name}
+				tools[name] = total
+			}
+			total.Calls += t.Calls
+			total.Errors += t.Errors
+		}
+	}
+
+	fillPeriods(periods, first, last, opts.Period)
+
+	report.Projects = sortedRows(projects, byCost)
+	report.Periods = sortedRows(periods, func(a, b ReportRow) bool { return a.Key < b.Key })
+	report.Models = sortedRows(models, byCost)
+	for _, t := range tools {
+		t.ErrorRate = float64(t.Errors) / float64(t.Calls)
+		report.Tools = append(report.Tools, *t)
+	}
+	sort.Slice(report.Tools, func(i, j int) bool {
+		a, b := report.Tools[i], report.Tools[j]
+		if a.Calls != b.Calls {
+			return a.Calls > b.Calls
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+func reportRow(rows map[string]*ReportRow, key string) *ReportRow {
+	if key == "" {
+		key = "(unknown)"
+	}
+	r, ok := rows[key]
+	if !ok {
+		r = &ReportRow{Key: key, Priced: true}
+		rows[key] = r
+	}
+	return r
+}
+
+// fillPeriods adds empty rows for the days or weeks between the timestamps
+// first and last that have no sessions, so charts show the gaps.
+func fillPeriods(rows map[string]*ReportRow, first, last int64, period string) {
+	if first == 0 {
+		return
+	}
+	days := 1
+	if period == "week" {
+		days = 7
+	}
+	start, end := time.UnixMilli(first), time.UnixMilli(last)
+	for t := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local); !t.After(end); t = t.AddDate(0, 0, days) {
+		reportRow(rows, periodKey(t.UnixMilli(), period))
+	}
+}
+
+func sortedRows(rows map[string]*ReportRow, less func(a, b ReportRow) bool) []ReportRow {
+	sorted := make([]ReportRow, 0, len(rows))
+	for _, r := range rows {
+		sorted = append(sorted, *r)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	return sorted
+}
+
+// byCost orders rows by cost, then by tokens for rows without a price.
+func byCost(a, b ReportRow) bool {
+	if a.Cost != b.Cost {
+		return a.Cost > b.Cost
+	}
+	if a.Usage.Tokens() != b.Usage.Tokens() {
+		return a.Usage.Tokens() > b.Usage.Tokens()
+	}
+	return a.Key < b.Key
+}
+
+// periodKey names the day (2025-01-31) or ISO week (2025-W05) of a
+// timestamp in local time.
+func periodKey(ms int64, period string) string {
+	t := time.UnixMilli(ms)
+	if period == "week" {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format("2006-01-02")
+}
+
+// collectToolStats counts tool_use blocks per tool name, and the ones whose
+// tool_result was an error.
+func collectToolStats(rows []sessionRow) map[string]ToolStats {
+	names := make(map[string]string)
+	var ids []string
+	failed := make(map[string]bool)
+	for _, row := range rows {
+		if row.Message == nil || row.Type != "assistant" && row.Type != "user" {
+			continue
+		}
+		var api apiMessage
+		if err := json.Unmarshal(row.Message, &api); err != nil {
+			continue
+		}
+		var blocks []contentBlockRaw
+		if err := json.Unmarshal(api.Content, &blocks); err != nil {
+			continue
+		}
+		for _, b := range blocks {
+			switch {
+			case b.Type == "tool_use" && row.Type == "assistant":
+				if _, ok := names[b.ID]; !ok {
+					names[b.ID] = b.Name
+					ids = append(ids, b.ID)
+				}
+			case b.Type == "tool_result" && b.IsError:
+				failed[b.ToolUseID] = true
+			}
+		}
+	}
+
+	stats := make(map[string]ToolStats)
+	for _, id := range ids {
+		t := stats[names[id]]
+		t.Name = names[id]
+		t.Calls++
+		if failed[id] {
+			t.Errors++
+		}
+		stats[t.Name] = t
+	}
+	return stats
+}
+
+// writeReport prints the report as plain-text tables.
+func writeReport(w io.Writer, r Report) {
+	fmt.Fprintf(w, "%d sessions, %d messages, %s tokens, estimated cost %s\n",
+		r.Total.Sessions, r.Total.Messages, groupDigits(r.Total.Usage.Tokens()), formatCost(r.Total.Cost, r.Total.Priced))
+
+	sections := []struct {
+		title string
+		rows  []ReportRow
+	}{
+		{"Project", r.Projects},
+		{map[string]string{"day": "Day", "week": "Week"}[r.Period], r.Periods},
+		{"Model", r.Models},
+	}
+	for _, sec := range sections {
+		if len(sec.rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%-40s  %8s  %8s  %14s  %14s  %14s  %14s  %10s\n", sec.title, "Sessions", "Messages", "Input", "Output", "Cache read", "Cache write", "Cost")
+		for _, row := range sec.rows {
+			fmt.Fprintf(w, "%-40s  %8d  %8d  %14s  %14s  %14s  %14s  %10s\n", row.Key, row.Sessions, row.Messages,
+				groupDigits(row.Usage.InputTokens), groupDigits(row.Usage.OutputTokens),
+				groupDigits(row.Usage.CacheReadTokens), groupDigits(row.Usage.CacheCreationTokens),
+				formatCost(row.Cost, row.Priced))
+		}
+	}
+
+	if len(r.Tools) > 0 {
+		fmt.Fprintf(w, "\n%-40s  %8s  %8s  %10s\n", "Tool", "Calls", "Errors", "Error rate")
+		for _, t := range r.Tools {
+			fmt.Fprintf(w, "%-40s  %8d  %8d  %9.1f%%\n", t.Name, t.Calls, t.Errors, t.ErrorRate*100)
+		}
+	}
+}
+
+// Report charts are drawn as inline SVG in a fixed coordinate space that
+// scales with the page. reportTemplate uses the same numbers.
+const (
+	chartWidth      = 640.0
+	chartLabelWidth = 170.0
+	chartValueWidth = 90.0
+	chartRowHeight  = 24.0
+	chartPlotHeight = 140.0
+	chartMaxBars    = 12
+)
+
+// barChart is a horizontal bar chart, one bar per row.
+type barChart struct {
+	Title  string
+	Height float64
+	Bars   []chartBar
+}
+
+type chartBar struct {
+	Label  string
+	Title  string // tooltip
+	Value  string
+	Y      float64 // top of the bar's row
+	Width  float64
+	Part   float64 // width of the highlighted share of the bar, e.g. errors
+	ValueX float64
+}
+
+// columnChart is a vertical bar chart over time.
+type columnChart struct {
+	Title    string
+	BarWidth float64
+	Columns  []chartColumn
+}
+
+type chartColumn struct {
+	X, Y, Height float64
+	Label        string // axis label, only on some columns
+	Title        string
+}
+
+type reportTable struct {
+	Title string
+	Rows  []ReportRow
+}
+
+type reportPage struct {
+	Report
+	Title     string
+	Theme     string
+	Generated string
+	Charts    []barChart
+	Timeline  columnChart
+	Tables    []reportTable
+}
+
+// RenderReport renders r as a self-contained HTML dashboard. Charts show
+// estimated cost, or token counts if no model had a price.
+func RenderReport(r Report, theme string) (string, error) {
+	tmpl, err := parseTemplates()
+	if err != nil {
+		return "", err
+	}
+	if theme == "" {
+		theme = "dark"
+	}
+	if _, err := themeChromaCSS(theme); err != nil {
+		return "", err
+	}
+
+	useCost := r.Total.Cost > 0
+	metric := func(row ReportRow) (float64, string) {
+		if useCost {
+			return row.Cost, formatCost(row.Cost, row.Priced)
+		}
+		return float64(row.Usage.Tokens()), formatTokens(row.Usage.Tokens())
+	}
+	metricName := "Tokens"
+	if useCost {
+		metricName = "Estimated cost"
+	}
+
+	page := reportPage{
+		Report:    r,
+		Title:     "Claude Code Usage",
+		Theme:     theme,
+		Generated: time.Now().Format("Jan 2, 2006 15:04"),
+	}
+
+	period := map[string]string{"day": "day", "week": "week"}[r.Period]
+	page.Timeline = columnChart{Title: metricName + " per " + period}
+	if n := len(r.Periods); n > 0 {
+		var top float64
+		for _, row := range r.Periods {
+			v, _ := metric(row)
+			top = max(top, v)
+		}
+		step := chartWidth / float64(n)
+		page.Timeline.BarWidth = min(step*0.8, 48)
+		labelEvery := (n + 7) / 8
+		for i, row := range r.Periods {
+			v, display := metric(row)
+			h := scale(v, top, chartPlotHeight)
+			col := chartColumn{
+				X:      float64(i)*step + (step-page.Timeline.BarWidth)/2,
+				Y:      chartPlotHeight - h,
+				Height: h,
+				Title:  fmt.Sprintf("%s: %s, %d sessions", row.Key, display, row.Sessions),
+			}
+			if i%labelEvery == 0 {
+				col.Label = row.Key
+			}
+			page.Timeline.Columns = append(page.Timeline.Columns, col)
+		}
+	}
+
+	projectBars := make([]chartBar, 0, len(r.Projects))
+	for _, row := range r.Projects {
+		v, display := metric(row)
+		projectBars = append(projectBars, chartBar{Label: filepath.Base(row.Key), Title: row.Key, Value: display, Width: v})
+	}
+	modelBars := make([]chartBar, 0, len(r.Models))
+	for _, row := range r.Models {
+		v, display := metric(row)
+		modelBars = append(modelBars, chartBar{Label: row.Key, Title: row.Key, Value: display, Width: v})
+	}
+	toolBars := make([]chartBar, 0, len(r.Tools))
+	for _, t := range r.Tools {
+		toolBars = append(toolBars, chartBar{
+			Label: t.Name,
+			Title: fmt.Sprintf("%s: %d calls, %d errors", t.Name, t.Calls, t.Errors),
+			Value: fmt.Sprintf("%d · %.0f%% err", t.Calls, t.ErrorRate*100),
+			Width: float64(t.Calls),
+			Part:  float64(t.Errors),
+		})
+	}
+	page.Charts = []barChart{
+		newBarChart(metricName+" by project", projectBars),
+		newBarChart(metricName+" by model", modelBars),
+		newBarChart("Tool calls and errors", toolBars),
+	}
+	page.Tables = []reportTable{
+		{"Projects", r.Projects},
+		{map[string]string{"day": "Days", "week": "Weeks"}[r.Period], r.Periods},
+		{"Models", r.Models},
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "report", page); err != nil {
+		return "", fmt.Errorf("execute template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// newBarChart lays out the first chartMaxBars bars, whose Width and Part
+// hold raw values on entry.
+func newBarChart(title string, bars []chartBar) barChart {
+	if len(bars) > chartMaxBars {
+		bars = bars[:chartMaxBars]
+	}
+	var top float64
+	for _, b := range bars {
+		top = max(top, b.Width)
+	}
+	area := chartWidth - chartLabelWidth - chartValueWidth
+	for i := range bars {
+		bars[i].Label = truncatePrompt(bars[i].Label, 24)
+		bars[i].Y = float64(i) * chartRowHeight
+		bars[i].Part = scale(bars[i].Part, top, area)
+		bars[i].Width = scale(bars[i].Width, top, area)
+		bars[i].ValueX = chartLabelWidth + bars[i].Width + 6
+	}
+	return barChart{Title: title, Height: float64(len(bars)) * chartRowHeight, Bars: bars}
+}
+
+func scale(v, top, size float64) float64 {
+	if top <= 0 {
+		return 0
+	}
+	return v / top * size
+}
+
+const reportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>{{.Title}}</title>
+{{template "favicon"}}
+<style>
+{{template "base-css" .}}
+:root{--max-w:960px}
+.session-meta{max-width:var(--max-w);margin:0 auto;padding:32px 24px 0}
+.session-title{font-size:1.35rem;font-weight:600;letter-spacing:-.02em;margin-bottom:6px}
+.session-info{color:var(--text-tertiary);font-size:.78rem}
+.report-cards{max-width:var(--max-w);margin:20px auto 0;padding:0 24px;display:grid;grid-template-columns:repeat(auto-fit,minmax(160px,1fr));gap:12px}
+.report-card{border:1px solid var(--border);border-radius:var(--radius);background:var(--surface);padding:14px 16px}
+.report-card-label{font-size:.72rem;color:var(--text-tertiary);text-transform:uppercase;letter-spacing:.04em}
+.report-card-value{font-size:1.3rem;font-weight:600;margin-top:2px}
+.report-section{max-width:var(--max-w);margin:0 auto;padding:28px 24px 0}
+.report-section h2{font-size:.95rem;font-weight:600;margin-bottom:12px}
+.report-chart{display:block;width:100%;height:auto;border:1px solid var(--border);border-radius:var(--radius);background:var(--surface);padding:14px}
+.report-chart text{fill:var(--text-secondary);font-size:11px;font-family:inherit}
+.report-chart .bar{fill:var(--accent)}
+.report-chart .bar-part{fill:var(--red)}
+.report-chart .axis{stroke:var(--border)}
+.report-table-wrap{overflow-x:auto}
+.report-table{width:100%;border-collapse:collapse;font-size:.78rem;font-variant-numeric:tabular-nums}
+.report-table th,.report-table td{padding:6px 8px;text-align:right;white-space:nowrap;border-top:1px solid var(--border)}
+.report-table th{color:var(--text-tertiary);font-weight:500;border-top:none}
+.report-table th:first-child,.report-table td:first-child{text-align:left;white-space:normal;word-break:break-all}
+@media(max-width:640px){
+  html{font-size:14px}
+  .topbar-inner,.session-meta,.report-cards,.report-section,.footer{padding-left:16px;padding-right:16px}
+}
+</style>
+</head>
+<body>
+
+{{template "topbar" .}}
+
+<div class="session-meta">
+  <h1 class="session-title">{{.Title}}</h1>
+  <div class="session-info">Generated {{.Generated}}</div>
+</div>
+
+<div class="report-cards">
+  <div class="report-card"><div class="report-card-label">Sessions</div><div class="report-card-value">{{.Total.Sessions}}</div></div>
+  <div class="report-card"><div class="report-card-label">Messages</div><div class="report-card-value">{{.Total.Messages}}</div></div>
+  <div class="report-card"><div class="report-card-label">Tokens</div><div class="report-card-value">{{tokens .Total.Usage.Tokens}}</div></div>
+  <div class="report-card"><div class="report-card-label">Estimated cost</div><div class="report-card-value">{{cost .Total.Cost .Total.Priced}}</div></div>
+</div>
+
+{{with .Timeline}}{{if .Columns}}<section class="report-section">
+  <h2>{{.Title}}</h2>
+  <svg class="report-chart" viewBox="0 0 640 160" role="img" aria-label="{{.Title}}">
+    <line class="axis" x1="0" y1="140" x2="640" y2="140"/>
+    {{range .Columns}}<rect class="bar" x="{{.X}}" y="{{.Y}}" width="{{$.Timeline.BarWidth}}" height="{{.Height}}" rx="2"><title>{{.Title}}</title></rect>
+    {{if .Label}}<text x="{{.X}}" y="156">{{.Label}}</text>{{end}}
+    {{end}}
+  </svg>
+</section>{{end}}{{end}}
+
+{{range .Charts}}{{if .Bars}}<section class="report-section">
+  <h2>{{.Title}}</h2>
+  <svg class="report-chart" viewBox="0 0 640 {{.Height}}" role="img" aria-label="{{.Title}}">
+    {{range .Bars}}<g transform="translate(0,{{.Y}})"><title>{{.Title}}</title>
+      <text x="0" y="16">{{.Label}}</text>
+      <rect class="bar" x="170" y="4" width="{{.Width}}" height="16" rx="2"/>
+      {{if .Part}}<rect class="bar-part" x="170" y="4" width="{{.Part}}" height="16" rx="2"/>{{end}}
+      <text x="{{.ValueX}}" y="16">{{.Value}}</text>
+    </g>
+    {{end}}
+  </svg>
+</section>{{end}}{{end}}
+
+{{range .Tables}}{{if .Rows}}<section class="report-section">
+  <h2>{{.Title}}</h2>
+  <div class="report-table-wrap"><table class="report-table">
+    <tr><th></th><th>Sessions</th><th>Messages</th><th>Input</th><th>Output</th><th>Cache read</th><th>Cache write</th><th>Cost</th></tr>
+    {{range .Rows}}<tr><td>{{.Key}}</td><td>{{.Sessions}}</td><td>{{.Messages}}</td><td>{{tokens .Usage.InputTokens}}</td><td>{{tokens .Usage.OutputTokens}}</td><td>{{tokens .Usage.CacheReadTokens}}</td><td>{{tokens .Usage.CacheCreationTokens}}</td><td>{{cost .Cost .Priced}}</td></tr>
+    {{end}}
+  </table></div>
+</section>{{end}}{{end}}
+
+{{if .Tools}}<section class="report-section">
+  <h2>Tools</h2>
+  <div class="report-table-wrap"><table class="report-table">
+    <tr><th>Tool</th><th>Calls</th><th>Errors</th><th>Error rate</th></tr>
+    {{range .Tools}}<tr><td>{{.Name}}</td><td>{{.Calls}}</td><td>{{.Errors}}</td><td>{{printf "%.1f%%" (percent .ErrorRate)}}</td></tr>
+    {{end}}
+  </table></div>
+</section>{{end}}
+
+{{template "footer"}}
+
+<script>
+{{template "theme-script" .}}</script>
+</body>
+</html>`
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeReportFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	day1 := time.Date(2025, 3, 3, 10, 0, 0, 0, time.Local).UnixMilli()
+	day2 := time.Date(2025, 3, 4, 10, 0, 0, 0, time.Local).UnixMilli()
+	writeTempFile(t, dir, "history.jsonl", strings.Join([]string{
+		`{"display":"a","timestamp":` + strconv.FormatInt(day1, 10) + `,"project":"/home/user/webapp","sessionId":"s1"}`,
+		`{"display":"b","timestamp":` + strconv.FormatInt(day2, 10) + `,"project":"/home/user/webapp","sessionId":"s2"}`,
+		`{"display":"c","timestamp":` + strconv.FormatInt(day2, 10) + `,"project":"/home/user/cli","sessionId":"s3"}`,
+		`{"display":"d","timestamp":` + strconv.FormatInt(day2, 10) + `,"project":"/home/user/cli","sessionId":"gone"}`,
+	}, "\n")+"\n")
+
+	sonnet := `{"type":"assistant","message":{"id":"m1","model":"claude-sonnet-4-5","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{}}],"usage":{"input_tokens":1000000,"output_tokens":0}}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","is_error":true,"content":"exit 1"}]}}
+{"type":"assistant","message":{"id":"m2","model":"claude-sonnet-4-5","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{}},{"type":"tool_use","id":"t3","name":"Read","input":{}}],"usage":{"input_tokens":0,"output_tokens":1000}}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":"ok"},{"type":"tool_result","tool_use_id":"t3","content":"ok"}]}}
+`
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-webapp", "s1.jsonl"), sonnet)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-webapp", "s2.jsonl"), sonnet)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-cli", "s3.jsonl"),
+		`{"type":"assistant","message":{"id":"m1","model":"claude-haiku-4-5","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":1000000,"output_tokens":0}}}
+`)
+	return dir
+}
+
+func TestBuildReport(t *testing.T) {
+	dir := writeReportFixture(t)
+
+	r, err := BuildReport([]string{dir}, ReportOpts{Period: "day", Prices: defaultPrices, Jobs: 2})
+	require.NoError(t, err)
+	require.Len(t, r.Skipped, 1)
+	assert.Contains(t, r.Skipped[0], "gone")
+
+	assert.Equal(t, 3, r.Total.Sessions)
+	assert.Equal(t, 5, r.Total.Messages)
+	assert.True(t, r.Total.Priced)
+	// Two sonnet sessions at $3 + $0.015 each, one haiku session at $1.
+	assert.InDelta(t, 7.03, r.Total.Cost, 1e-9)
+
+	require.Len(t, r.Projects, 2)
+	assert.Equal(t, "/home/user/webapp", r.Projects[0].Key)
+	assert.Equal(t, 2, r.Projects[0].Sessions)
+
+	require.Len(t, r.Periods, 2)
+	assert.Equal(t, "2025-03-03", r.Periods[0].Key)
+	assert.Equal(t, 1, r.Periods[0].Sessions)
+	assert.Equal(t, 2, r.Periods[1].Sessions)
+
+	require.Len(t, r.Models, 2)
+	assert.Equal(t, "claude-sonnet-4-5", r.Models[0].Key)
+	assert.Equal(t, 4, r.Models[0].Messages)
+
+	assert.Equal(t, []ToolStats{
+		{Name: "Bash", Calls: 4, Errors: 2, ErrorRate: 0.5},
+		{Name: "Read", Calls: 2},
+	}, r.Tools)
+}
+
+func TestBuildReport_FillsEmptyPeriods(t *testing.T) {
+	dir := t.TempDir()
+	mon := time.Date(2025, 3, 3, 10, 0, 0, 0, time.Local).UnixMilli()
+	thu := time.Date(2025, 3, 20, 9, 0, 0, 0, time.Local).UnixMilli()
+	writeTempFile(t, dir, "history.jsonl", strings.Join([]string{
+		`{"display":"a","timestamp":` + strconv.FormatInt(mon, 10) + `,"project":"/p","sessionId":"s1"}`,
+		`{"display":"b","timestamp":` + strconv.FormatInt(thu, 10) + `,"project":"/p","sessionId":"s2"}`,
+	}, "\n")+"\n")
+	row := `{"type":"assistant","message":{"id":"m1","model":"claude-haiku-4-5","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":10,"output_tokens":0}}}
+`
+	writeTempFile(t, dir, filepath.Join("projects", "-p", "s1.jsonl"), row)
+	writeTempFile(t, dir, filepath.Join("projects", "-p", "s2.jsonl"), row)
+
+	r, err := BuildReport([]string{dir}, ReportOpts{Period: "day", Prices: defaultPrices, Jobs: 1})
+	require.NoError(t, err)
+	require.Len(t, r.Periods, 18)
+	assert.Equal(t, "2025-03-03", r.Periods[0].Key)
+	assert.Equal(t, ReportRow{Key: "2025-03-04", Priced: true}, r.Periods[1])
+	assert.Equal(t, "2025-03-20", r.Periods[17].Key)
+	assert.Equal(t, 1, r.Periods[17].Sessions)
+
+	r, err = BuildReport([]string{dir}, ReportOpts{Period: "week", Prices: defaultPrices, Jobs: 1})
+	require.NoError(t, err)
+	var keys []string
+	for _, p := range r.Periods {
+		keys = append(keys, p.Key)
+	}
+	assert.Equal(t, []string{"2025-W10", "2025-W11", "2025-W12"}, keys)
+	assert.Zero(t, r.Periods[1].Sessions)
+}
+
+func TestBuildReport_Filter(t *testing.T) {
+	dir := writeReportFixture(t)
+
+	r, err := BuildReport([]string{dir}, ReportOpts{Filter: listFilter{Project: "cli"}, Period: "week", Prices: defaultPrices, Jobs: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Total.Sessions)
+	require.Len(t, r.Periods, 1)
+	assert.Equal(t, "2025-W10", r.Periods[0].Key)
+	assert.Empty(t, r.Tools)
+}
+
+func TestPeriodKey(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local).UnixMilli()
+	assert.Equal(t, "2025-01-01", periodKey(ts, "day"))
+	assert.Equal(t, "2025-W01", periodKey(ts, "week"))
+
+	ts = time.Date(2024, 12, 30, 12, 0, 0, 0, time.Local).UnixMilli()
+	assert.Equal(t, "2025-W01", periodKey(ts, "week"), "ISO weeks can start in the previous year")
+}
+
+func TestWriteReport(t *testing.T) {
+	dir := writeReportFixture(t)
+	r, err := BuildReport([]string{dir}, ReportOpts{Period: "week", Prices: defaultPrices, Jobs: 4})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	writeReport(&buf, r)
+	out := buf.String()
+	assert.Contains(t, out, "3 sessions, 5 messages")
+	assert.Contains(t, out, "$7.03")
+	assert.Contains(t, out, "Week ")
+	assert.Contains(t, out, "/home/user/webapp")
+	assert.Regexp(t, `Bash\s+4\s+2\s+50\.0%`, out)
+}
+
+func TestRenderReport(t *testing.T) {
+	dir := writeReportFixture(t)
+	r, err := BuildReport([]string{dir}, ReportOpts{Period: "day", Prices: defaultPrices, Jobs: 4})
+	require.NoError(t, err)
+
+	page, err := RenderReport(r, "auto")
+	require.NoError(t, err)
+	assert.Contains(t, page, "<!DOCTYPE html>")
+	assert.Contains(t, page, "Estimated cost per day")
+	assert.Contains(t, page, "Estimated cost by project")
+	assert.Contains(t, page, `<rect class="bar-part"`)
+	assert.Contains(t, page, "2025-03-04")
+	assert.Contains(t, page, "$7.03")
+	assert.Contains(t, page, "toggleTheme")
+	assert.NotContains(t, page, "<script src")
+	assert.NotContains(t, page, "<link rel=\"stylesheet\"")
+}
+
+func TestRenderReport_TokensWithoutPrices(t *testing.T) {
+	dir := writeReportFixture(t)
+	r, err := BuildReport([]string{dir}, ReportOpts{Period: "day", Prices: PriceTable{}, Jobs: 4})
+	require.NoError(t, err)
+	assert.False(t, r.Total.Priced)
+
+	page, err := RenderReport(r, "dark")
+	require.NoError(t, err)
+	assert.Contains(t, page, "Tokens by model")
+	assert.Contains(t, page, ">?<")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// AmbiguousSessionError is returned when a session ID prefix matches more
+// than one session.
+type AmbiguousSessionError struct {
+	Prefix     string
+	Candidates []SessionSummary
+}
+
+func (e *AmbiguousSessionError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "session ID prefix %q is ambiguous; candidates:", e.Prefix)
+	for _, c := range e.Candidates {
+		fmt.Fprintf(&b, "\n  %s", c.ID)
+		if c.Project != "" {
+			fmt.Fprintf(&b, "  %s", filepath.Base(c.Project))
+		}
+		if c.FirstPrompt != "" {
+			fmt.Fprintf(&b, "  %s", truncatePrompt(c.FirstPrompt, 50))
+		}
+	}
+	return b.String()
+}
+
+// ResolveSessionID turns what the user typed into a full session ID. ref may
+// be a full ID, a unique ID prefix, "latest" or "latest~N" (the Nth session
+// before the latest one). The latest aliases follow ListSessions order and
+// only consider sessions whose project path contains project.
+func ResolveSessionID(claudeDirs []string, ref, project string) (string, error) {
+	if ref == "" {
+		return "", fmt.Errorf("empty session ID")
+	}
+	if ref == "latest" || strings.HasPrefix(ref, "latest~") {
+		return resolveLatest(claudeDirs, ref, project)
+	}
+
+	if strings.ContainsAny(ref, `/\`) {
+		return "", fmt.Errorf("session %s not found", ref)
+	}
+	if _, err := FindSessionPath(claudeDirs, ref); err == nil {
+		return ref, nil
+	}
+
+	files, err := globSessions(claudeDirs, globEscape(ref)+"*")
+	if err != nil {
+		return "", err
+	}
+	paths := make(map[string]string)
+	var ids []string
+	for _, f := range files {
+		id := strings.TrimSuffix(filepath.Base(f), ".jsonl")
+		if isSubagentFile(id) {
+			continue
+		}
+		if _, ok := paths[id]; !ok {
+			paths[id] = f
+			ids = append(ids, id)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("session %s not found", ref)
+	case 1:
+		return ids[0], nil
+	}
+
+	sort.Strings(ids)
+	known := make(map[string]SessionSummary)
+	if sessions, err := ParseHistory(claudeDirs); err == nil {
+		for _, s := range sessions {
+			known[s.ID] = s
+		}
+	}
+	candidates := make([]SessionSummary, len(ids))
+	for i, id := range ids {
+		c, ok := known[id]
+		if !ok {
+			c, _ = peekSession(paths[id])
+		}
+		c.ID = id
+		candidates[i] = c
+	}
+	return "", &AmbiguousSessionError{Prefix: ref, Candidates: candidates}
+}
+
+func resolveLatest(claudeDirs []string, ref, project string) (string, error) {
+	n := 0
+	if rest, ok := strings.CutPrefix(ref, "latest~"); ok {
+		var err error
+		if n, err = strconv.Atoi(rest); err != nil || n < 0 {
+			return "", fmt.Errorf("invalid session alias %q (want latest or latest~N)", ref)
+		}
+	}
+
+	sessions, err := ListSessions(claudeDirs)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("no sessions to resolve %q", ref)
+		}
+		return "", err
+	}
+	var matched []SessionSummary
+	for _, s := range sessions {
+		if project == "" || strings.Contains(strings.ToLower(s.Project), strings.ToLower(project)) {
+			matched = append(matched, s)
+		}
+	}
+	if n >= len(matched) {
+		if project != "" {
+			return "", fmt.Errorf("%s: only %d sessions match project %q", ref, len(matched), project)
+		}
+		return "", fmt.Errorf("%s: only %d sessions in history", ref, len(matched))
+	}
+	return matched[n].ID, nil
+}
+
+// validSessionID reports whether id is safe to use as a file name. IDs come
+// from history.jsonl, which nothing else checks.
+func validSessionID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+// globEscape escapes the characters filepath.Match treats specially.
+func globEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+	return r.Replace(s)
+}
+
+func truncatePrompt(s string, n int) string {
+	s = oneLine(s)
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
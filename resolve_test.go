@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeResolveFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTempFile(t, dir, "history.jsonl",
+		`{"display":"oldest","timestamp":1000,"project":"/home/user/webapp","sessionId":"abc11111"}
+{"display":"middle","timestamp":2000,"project":"/home/user/cli","sessionId":"abc22222"}
+{"display":"newest","timestamp":3000,"project":"/home/user/webapp","sessionId":"def33333"}
+`)
+	for _, id := range []string{"abc11111", "abc22222", "def33333"} {
+		writeTempFile(t, dir, filepath.Join("projects", "-home-user-x", id+".jsonl"), "")
+	}
+	return dir
+}
+
+func TestResolveSessionID_ExactAndUniquePrefix(t *testing.T) {
+	dir := writeResolveFixture(t)
+
+	id, err := ResolveSessionID([]string{dir}, "abc22222", "")
+	require.NoError(t, err)
+	assert.Equal(t, "abc22222", id)
+
+	id, err = ResolveSessionID([]string{dir}, "d", "")
+	require.NoError(t, err)
+	assert.Equal(t, "def33333", id)
+}
+
+func TestResolveSessionID_AmbiguousPrefixListsCandidates(t *testing.T) {
+	dir := writeResolveFixture(t)
+
+	_, err := ResolveSessionID([]string{dir}, "abc", "")
+	var ambiguous *AmbiguousSessionError
+	require.ErrorAs(t, err, &ambiguous)
+	require.Len(t, ambiguous.Candidates, 2)
+	assert.Equal(t, "abc11111", ambiguous.Candidates[0].ID)
+	assert.Contains(t, err.Error(), "abc22222  cli  middle")
+}
+
+func TestResolveSessionID_NotFound(t *testing.T) {
+	dir := writeResolveFixture(t)
+
+	_, err := ResolveSessionID([]string{dir}, "zzz", "")
+	assert.EqualError(t, err, "session zzz not found")
+
+	_, err = ResolveSessionID([]string{dir}, "../abc", "")
+	assert.Error(t, err)
+
+	_, err = ResolveSessionID([]string{dir}, "a*", "")
+	assert.Error(t, err)
+}
+
+func TestResolveSessionID_Latest(t *testing.T) {
+	dir := writeResolveFixture(t)
+
+	cases := []struct {
+		ref, project, want string
+	}{
+		{"latest", "", "def33333"},
+		{"latest~1", "", "abc22222"},
+		{"latest~2", "", "abc11111"},
+		{"latest~1", "webapp", "abc11111"},
+		{"latest", "CLI", "abc22222"},
+	}
+	for _, c := range cases {
+		id, err := ResolveSessionID([]string{dir}, c.ref, c.project)
+		require.NoError(t, err, c.ref)
+		assert.Equal(t, c.want, id, "%s --project %q", c.ref, c.project)
+	}
+}
+
+func TestResolveSessionID_LatestErrors(t *testing.T) {
+	dir := writeResolveFixture(t)
+
+	_, err := ResolveSessionID([]string{dir}, "latest~3", "")
+	assert.ErrorContains(t, err, "only 3 sessions")
+
+	_, err = ResolveSessionID([]string{dir}, "latest~x", "")
+	assert.ErrorContains(t, err, "invalid session alias")
+
+	_, err = ResolveSessionID([]string{t.TempDir()}, "latest", "")
+	assert.ErrorContains(t, err, "no sessions")
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SessionFileInfo is what a quick pass over a session file reveals without
+// rebuilding the conversation.
+type SessionFileInfo struct {
+	Path         string
+	Size         int64
+	MessageCount int // messages a plain export of the session shows
+	LastActivity time.Time
+}
+
+func ScanSessionFile(path string) (SessionFileInfo, error) {
+	info := SessionFileInfo{Path: path}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return info, fmt.Errorf("stat session: %w", err)
+	}
+	info.Size = stat.Size()
+
+	var rf rowFile
+	if _, err := rf.read(path); err != nil {
+		return info, err
+	}
+	for _, row := range rf.rows {
+		if ts, err := time.Parse(time.RFC3339, row.Timestamp); err == nil && ts.After(info.LastActivity) {
+			info.LastActivity = ts
+		}
+	}
+	// Count the way the exporter does, so abandoned branches and merged
+	// assistant rows don't inflate the number.
+	info.MessageCount = len(buildSession(rf.rows, ParseOpts{}))
+	if info.LastActivity.IsZero() {
+		info.LastActivity = stat.ModTime()
+	}
+	return info, nil
+}
+
+// sessionFiles maps every session ID under projects/ to its file path.
+// Subagent transcripts are left out.
+func sessionFiles(claudeDirs []string) (map[string]string, error) {
+	files, err := globSessions(claudeDirs, "*")
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]string, len(files))
+	for _, f := range files {
+		id := strings.TrimSuffix(filepath.Base(f), ".jsonl")
+		if isSubagentFile(id) {
+			continue
+		}
+		if _, ok := paths[id]; !ok {
+			paths[id] = f
+		}
+	}
+	return paths, nil
+}
+
+// isSubagentFile reports whether id names a subagent transcript, which older
+// Claude Code versions store next to the sessions as agent-<id>.jsonl.
+func isSubagentFile(id string) bool {
+	return strings.HasPrefix(id, "agent-")
+}
+
+// checkProjectsDir returns an error if none of the Claude dirs has a
+// projects directory.
+func checkProjectsDir(claudeDirs []string) error {
+	err := fmt.Errorf("no Claude directory given")
+	for _, dir := range claudeDirs {
+		if _, err = os.Stat(filepath.Join(dir, "projects")); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("read projects dir: %w", err)
+}
+
+// globSessions returns the session files whose ID matches pattern, in
+// directory precedence order.
+func globSessions(claudeDirs []string, pattern string) ([]string, error) {
+	var files []string
+	for _, dir := range claudeDirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "projects", "*", pattern+".jsonl"))
+		if err != nil {
+			return nil, fmt.Errorf("list sessions: %w", err)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// ListSessions returns every session, newest first: the ones recorded in
+// history.jsonl plus session files under projects/ that history doesn't
+// mention (resumed, SDK-driven or synced-in sessions). For those, project,
+// first prompt and start time come from the session rows.
+func ListSessions(claudeDirs []string) ([]SessionSummary, error) {
+	sessions, historyErr := ParseHistory(claudeDirs)
+	if historyErr != nil && !errors.Is(historyErr, os.ErrNotExist) {
+		return nil, historyErr
+	}
+	paths, err := sessionFiles(claudeDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		known[s.ID] = true
+	}
+	var discovered []SessionSummary
+	for id, path := range paths {
+		if known[id] {
+			continue
+		}
+		s, err := peekSession(path)
+		if err != nil {
+			continue
+		}
+		s.ID = id
+		discovered = append(discovered, s)
+	}
+	if historyErr != nil && len(discovered) == 0 {
+		return nil, historyErr
+	}
+
+	sessions = append(sessions, discovered...)
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if sessions[i].Timestamp != sessions[j].Timestamp {
+			return sessions[i].Timestamp > sessions[j].Timestamp
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// peekSession reads just enough of a session file to summarize it like a
+// history entry. Files without a timestamp fall back to their modification
+// time, and files without a cwd to their project directory name. Files with
+// no main-conversation user or assistant row, such as summary-only ones,
+// aren't sessions and return an error.
+func peekSession(path string) (SessionSummary, error) {
+	var sum sessionSummarizer
+	f, err := os.Open(path)
+	if err != nil {
+		return sum.s, fmt.Errorf("open session: %w", err)
+	}
+	defer f.Close()
+
+	// Like rowFile, put no limit on line length: a single pasted image can
+	// make a row many megabytes long.
+	hasMessages := false
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		var row sessionRow
+		if json.Unmarshal(line, &row) == nil {
+			if !row.IsSidechain && !row.IsMeta && (row.Type == "user" || row.Type == "assistant") {
+				hasMessages = true
+			}
+			if sum.add(row) {
+				break
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return sum.s, fmt.Errorf("read session: %w", err)
+		}
+	}
+	if !hasMessages {
+		return sum.s, fmt.Errorf("%s has no messages", path)
+	}
+	s := sum.s
+	if s.Timestamp == 0 {
+		if info, err := f.Stat(); err == nil {
+			s.Timestamp = info.ModTime().UnixMilli()
+		}
+	}
+	if s.Project == "" {
+		// The encoded project directory name is lossy but better than nothing.
+		s.Project = filepath.Base(filepath.Dir(path))
+	}
+	return s, nil
+}
+
+// sessionSummarizer collects a session's ID, start time, working directory
+// and first prompt from its leading rows.
+type sessionSummarizer struct {
+	s SessionSummary
+}
+
+// add takes the next row and reports whether the summary is complete.
+func (sum *sessionSummarizer) add(row sessionRow) bool {
+	s := &sum.s
+	if s.ID == "" {
+		s.ID = row.SessionID
+	}
+	if s.Timestamp == 0 {
+		if ts, err := time.Parse(time.RFC3339, row.Timestamp); err == nil {
+			s.Timestamp = ts.UnixMilli()
+		}
+	}
+	if s.Project == "" {
+		s.Project = row.Cwd
+	}
+	if s.FirstPrompt == "" && row.Type == "user" && !row.IsSidechain {
+		if msg := parseUserRow(row, ParseOpts{}); msg != nil {
+			s.FirstPrompt = firstUserText([]Message{*msg})
+		}
+	}
+	return s.ID != "" && s.Timestamp != 0 && s.Project != "" && s.FirstPrompt != ""
+}
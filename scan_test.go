@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanSessionFile_CountsAndLastActivity(t *testing.T) {
+	path := writeSession(t, `{"type":"user","uuid":"u1","timestamp":"2025-01-02T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"type":"assistant","uuid":"a1","timestamp":"2025-01-02T10:00:03Z","message":{"id":"m1","role":"assistant","content":[{"type":"text","text":"Hi"}]}}
+{"type":"assistant","uuid":"a2","timestamp":"2025-01-02T10:00:04Z","message":{"id":"m1","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{}}]}}
+{"type":"user","uuid":"u2","timestamp":"2025-01-02T10:00:05Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}
+{"type":"user","uuid":"u3","isMeta":true,"timestamp":"2025-01-02T10:00:06Z","message":{"role":"user","content":"meta"}}
+{"type":"user","uuid":"s1","isSidechain":true,"timestamp":"2025-01-02T10:00:07Z","message":{"role":"user","content":"subagent prompt"}}
+{"type":"summary","summary":"Greeting"}
+`)
+
+	info, err := ScanSessionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, info.Path)
+	assert.Positive(t, info.Size)
+	assert.Equal(t, 2, info.MessageCount)
+	assert.Equal(t, time.Date(2025, 1, 2, 10, 0, 7, 0, time.UTC), info.LastActivity.UTC())
+}
+
+func TestScanSessionFile_MatchesExportedCount(t *testing.T) {
+	long := strings.Repeat("x", 11*1024*1024)
+	path := writeSession(t, `{"type":"user","uuid":"u1","timestamp":"2025-01-02T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","timestamp":"2025-01-02T10:00:03Z","message":{"id":"m1","role":"assistant","content":[{"type":"text","text":"abandoned"}]}}
+{"type":"assistant","uuid":"a2","parentUuid":"u1","timestamp":"2025-01-02T10:00:04Z","message":{"id":"m2","role":"assistant","content":[{"type":"text","text":"retried"}]}}
+{"type":"user","uuid":"u2","parentUuid":"a2","timestamp":"2025-01-02T10:00:05Z","message":{"role":"user","content":"`+long+`"}}
+`)
+
+	info, err := ScanSessionFile(path)
+	require.NoError(t, err)
+	msgs, err := ParseSession(path, ParseOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, len(msgs), info.MessageCount)
+	assert.Equal(t, 3, info.MessageCount)
+	assert.Equal(t, time.Date(2025, 1, 2, 10, 0, 5, 0, time.UTC), info.LastActivity.UTC())
+}
+
+func TestScanSessionFile_MissingFile(t *testing.T) {
+	_, err := ScanSessionFile("/nonexistent/session.jsonl")
+	assert.Error(t, err)
+}
+
+func TestListSessions_MergesUndiscoveredSessionFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTempFile(t, dir, "history.jsonl",
+		`{"display":"from history","timestamp":1735812000000,"project":"/home/user/webapp","sessionId":"h1"}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-webapp", "h1.jsonl"),
+		`{"type":"user","uuid":"u1","cwd":"/elsewhere","timestamp":"2025-01-02T10:00:00Z","message":{"role":"user","content":"ignored, history wins"}}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-sdk-tool", "d1.jsonl"),
+		`{"type":"summary","summary":"Resumed"}
+{"type":"user","uuid":"u0","isMeta":true,"cwd":"/home/user/sdk-tool","timestamp":"2025-01-03T08:00:00Z","message":{"role":"user","content":"meta"}}
+{"type":"user","uuid":"u1","cwd":"/home/user/sdk-tool","timestamp":"2025-01-03T08:00:01Z","message":{"role":"user","content":[{"type":"text","text":"  discovered prompt "}]}}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-bare", "d2.jsonl"),
+		`{"type":"user","uuid":"u1","message":{"role":"user","content":"no cwd or time"}}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-bare", "summary-only.jsonl"),
+		`{"type":"summary","summary":"Compacted","leafUuid":"x"}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-bare", "sidechain-only.jsonl"),
+		`{"type":"user","uuid":"s1","isSidechain":true,"message":{"role":"user","content":"subagent prompt"}}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-bare", "agent-1a2b.jsonl"),
+		`{"type":"user","uuid":"s1","message":{"role":"user","content":"subagent prompt"}}
+`)
+
+	sessions, err := ListSessions([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+
+	byID := make(map[string]SessionSummary)
+	for _, s := range sessions {
+		byID[s.ID] = s
+	}
+	assert.Equal(t, "from history", byID["h1"].FirstPrompt)
+	assert.Equal(t, "/home/user/webapp", byID["h1"].Project)
+
+	d1 := byID["d1"]
+	assert.Equal(t, "/home/user/sdk-tool", d1.Project)
+	assert.Equal(t, "discovered prompt", d1.FirstPrompt)
+	assert.Equal(t, time.Date(2025, 1, 3, 8, 0, 0, 0, time.UTC).UnixMilli(), d1.Timestamp)
+	assert.Equal(t, "d1", sessions[1].ID, "sorted newest first")
+
+	assert.Equal(t, "-home-user-bare", byID["d2"].Project)
+	assert.Positive(t, byID["d2"].Timestamp)
+}
+
+func TestListSessions_WithoutHistoryFile(t *testing.T) {
+	dir := t.TempDir()
+	_, err := ListSessions([]string{dir})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	writeTempFile(t, dir, filepath.Join("projects", "-p", "d1.jsonl"),
+		`{"type":"user","uuid":"u1","message":{"role":"user","content":"hi"}}
+`)
+	sessions, err := ListSessions([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "hi", sessions[0].FirstPrompt)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type SearchOpts struct {
+	Query         string
+	Regex         bool
+	CaseSensitive bool
+	IncludeTools  bool
+}
+
+type SearchHit struct {
+	SessionID string
+	Project   string
+	Timestamp string // ISO 8601 timestamp of the matching message
+	Role      string
+	Snippet   string
+	Match     [2]int // byte offsets of the match within Snippet
+}
+
+// SearchResult holds the matches and the sessions that couldn't be read.
+type SearchResult struct {
+	Hits    []SearchHit
+	Skipped []string // "<id>: <reason>"
+}
+
+const snippetContext = 40
+
+func SearchSessions(claudeDirs []string, opts SearchOpts) (SearchResult, error) {
+	var result SearchResult
+	re, err := searchPattern(opts)
+	if err != nil {
+		return result, err
+	}
+
+	paths, err := sessionFiles(claudeDirs)
+	if err != nil {
+		return result, err
+	}
+	if len(paths) == 0 {
+		if err := checkProjectsDir(claudeDirs); err != nil {
+			return result, err
+		}
+	}
+	files := slices.Sorted(maps.Values(paths))
+
+	// Newest sessions first.
+	modTimes := make(map[string]int64, len(files))
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			modTimes[f] = info.ModTime().UnixNano()
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return modTimes[files[i]] > modTimes[files[j]] })
+
+	projects := make(map[string]string)
+	if sessions, err := ListSessions(claudeDirs); err == nil {
+		for _, s := range sessions {
+			projects[s.ID] = filepath.Base(s.Project)
+		}
+	}
+
+	for _, f := range files {
+		id := strings.TrimSuffix(filepath.Base(f), ".jsonl")
+		msgs, err := ParseSession(f, ParseOpts{IncludeTools: opts.IncludeTools})
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", id, err))
+			continue
+		}
+		project, ok := projects[id]
+		if !ok {
+			project = filepath.Base(filepath.Dir(f))
+		}
+		searchMessages(msgs, re, opts, func(msg Message, text string, loc []int) {
+			snippet, match := makeSnippet(text, loc)
+			result.Hits = append(result.Hits, SearchHit{
+				SessionID: id,
+				Project:   project,
+				Timestamp: msg.Timestamp,
+				Role:      msg.Role,
+				Snippet:   snippet,
+				Match:     match,
+			})
+		})
+	}
+	return result, nil
+}
+
+func searchPattern(opts SearchOpts) (*regexp.Regexp, error) {
+	if opts.Query == "" {
+		return nil, fmt.Errorf("empty search query")
+	}
+	pattern := opts.Query
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !opts.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// searchMessages reports the first match in each searchable piece of text,
+// including abandoned branches and subagent conversations.
+func searchMessages(msgs []Message, re *regexp.Regexp, opts SearchOpts, hit func(Message, string, []int)) {
+	check := func(msg Message, text string) {
+		if loc := re.FindStringIndex(text); loc != nil && loc[1] > loc[0] {
+			hit(msg, text, loc)
+		}
+	}
+	for _, msg := range msgs {
+		for _, b := range msg.Blocks {
+			switch b.Type {
+			case "text":
+				check(msg, b.Text)
+			case "tool_use":
+				if !opts.IncludeTools {
+					continue
+				}
+				check(msg, b.ToolInput)
+				if b.Result != nil {
+					check(msg, b.Result.Text)
+				}
+				searchMessages(b.Sidechain, re, opts, hit)
+			case "tool_result":
+				if opts.IncludeTools {
+					check(msg, b.Text)
+				}
+			}
+		}
+		for _, alt := range msg.Branches {
+			searchMessages(alt, re, opts, hit)
+		}
+	}
+}
+
+// makeSnippet cuts the text around loc down to one line of context and
+// returns it with the match's position inside it.
+func makeSnippet(text string, loc []int) (string, [2]int) {
+	start := max(0, loc[0]-snippetContext)
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	end := min(len(text), loc[1]+snippetContext)
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	before := oneLine(text[start:loc[0]])
+	if start > 0 {
+		before = "…" + before
+	}
+	if loc[0] > start && strings.TrimSpace(text[loc[0]-1:loc[0]]) == "" {
+		before += " "
+	}
+	match := oneLine(text[loc[0]:loc[1]])
+	after := oneLine(text[loc[1]:end])
+	if loc[1] < end && strings.TrimSpace(text[loc[1]:loc[1]+1]) == "" {
+		after = " " + after
+	}
+	if end < len(text) {
+		after += "…"
+	}
+	return before + match + after, [2]int{len(before), len(before) + len(match)}
+}
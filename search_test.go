@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSearchFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTempFile(t, dir, "history.jsonl",
+		`{"display":"fix it","timestamp":1000,"project":"/home/user/webapp","sessionId":"s1"}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-webapp", "s1.jsonl"),
+		`{"type":"user","uuid":"u1","timestamp":"2025-01-02T10:00:00Z","message":{"role":"user","content":"There is a Race Condition in the cache"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","timestamp":"2025-01-02T10:00:05Z","message":{"id":"m1","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"go test -race ./..."}}]}}
+{"type":"user","uuid":"u2","parentUuid":"a1","timestamp":"2025-01-02T10:00:09Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"WARNING: DATA RACE"}]}}
+{"type":"assistant","uuid":"a2","parentUuid":"u2","timestamp":"2025-01-02T10:00:12Z","message":{"id":"m2","role":"assistant","content":[{"type":"text","text":"Fixed by adding a mutex."}]}}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-other", "s2.jsonl"),
+		`{"type":"user","uuid":"u1","timestamp":"2025-01-03T08:00:00Z","message":{"role":"user","content":"unrelated question"}}
+`)
+	return dir
+}
+
+func TestSearchSessions_CaseInsensitiveByDefault(t *testing.T) {
+	dir := writeSearchFixture(t)
+
+	result, err := SearchSessions([]string{dir}, SearchOpts{Query: "race condition"})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "s1", result.Hits[0].SessionID)
+	assert.Equal(t, "webapp", result.Hits[0].Project)
+	assert.Equal(t, "user", result.Hits[0].Role)
+	assert.Equal(t, "2025-01-02T10:00:00Z", result.Hits[0].Timestamp)
+	assert.Equal(t, "Race Condition", result.Hits[0].Snippet[result.Hits[0].Match[0]:result.Hits[0].Match[1]])
+}
+
+func TestSearchSessions_CaseSensitive(t *testing.T) {
+	dir := writeSearchFixture(t)
+
+	result, err := SearchSessions([]string{dir}, SearchOpts{Query: "race condition", CaseSensitive: true})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+}
+
+func TestSearchSessions_ToolContentOnlyWhenRequested(t *testing.T) {
+	dir := writeSearchFixture(t)
+
+	result, err := SearchSessions([]string{dir}, SearchOpts{Query: "data race"})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+
+	result, err = SearchSessions([]string{dir}, SearchOpts{Query: "data race", IncludeTools: true})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "assistant", result.Hits[0].Role)
+}
+
+func TestSearchSessions_Regex(t *testing.T) {
+	dir := writeSearchFixture(t)
+
+	result, err := SearchSessions([]string{dir}, SearchOpts{Query: `mut(ex|ant)`, Regex: true})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "Fixed by adding a mutex.", result.Hits[0].Snippet)
+
+	_, err = SearchSessions([]string{dir}, SearchOpts{Query: `(`, Regex: true})
+	assert.Error(t, err)
+}
+
+func TestSearchSessions_NoProjectsDir(t *testing.T) {
+	_, err := SearchSessions([]string{t.TempDir()}, SearchOpts{Query: "x"})
+	assert.Error(t, err)
+}
+
+func TestSearchSessions_SkipsUnreadableSessions(t *testing.T) {
+	dir := writeSearchFixture(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "projects", "-home-user-other", "broken.jsonl"), 0o755))
+
+	result, err := SearchSessions([]string{dir}, SearchOpts{Query: "race condition"})
+	require.NoError(t, err)
+	assert.Len(t, result.Hits, 1)
+	require.Len(t, result.Skipped, 1)
+	assert.Contains(t, result.Skipped[0], "broken: ")
+}
+
+func TestMakeSnippet_TrimsContextAndFlattensWhitespace(t *testing.T) {
+	text := "line one\npadding padding padding padding padding padding\nthe needle is here\nand more text after the needle that goes on and on"
+	loc := []int{len("line one\npadding padding padding padding padding padding\nthe "), 0}
+	loc[1] = loc[0] + len("needle")
+
+	snippet, match := makeSnippet(text, loc)
+	assert.Equal(t, "needle", snippet[match[0]:match[1]])
+	assert.True(t, len(snippet) < len(text))
+	assert.NotContains(t, snippet, "\n")
+	assert.Contains(t, snippet, "…")
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type server struct {
+	claudeDirs []string
+	theme      string
+	loc        *time.Location // time zone of dates and message times
+
+	// With watch set, session pages stay open on a stream of server-sent
+	// events and reload when their session file grows. Tails are shared by
+	// the requests currently using them and dropped once the last one ends.
+	watch    bool
+	interval time.Duration
+	mu       sync.Mutex
+	tails    map[string]*sharedTail
+
+	mux *http.ServeMux
+}
+
+func newServer(claudeDirs []string, theme string, loc *time.Location, watch bool) *server {
+	s := &server{
+		claudeDirs: claudeDirs,
+		theme:      theme,
+		loc:        loc,
+		watch:      watch,
+		interval:   watchInterval,
+		tails:      make(map[string]*sharedTail),
+		mux:        http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /session/{id}", s.handleSession)
+	if watch {
+		s.mux.HandleFunc("GET /session/{id}/events", s.handleEvents)
+	}
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := &IndexFilter{
+		Project:         q.Get("project"),
+		Sort:            q.Get("sort"),
+		IncludeTools:    queryBool(q, "tools"),
+		IncludeThinking: queryBool(q, "thinking"),
+	}
+	theme, ok := s.pageTheme(w, q)
+	if !ok {
+		return
+	}
+
+	sessions, err := ListSessions(s.claudeDirs)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	link := url.Values{}
+	if filter.IncludeTools {
+		link.Set("tools", "1")
+	}
+	if filter.IncludeThinking {
+		link.Set("thinking", "1")
+	}
+	if q.Has("theme") {
+		link.Set("theme", theme)
+		filter.Theme = theme
+	}
+
+	var entries []IndexEntry
+	for _, sess := range sessions {
+		if filter.Project != "" && !strings.Contains(strings.ToLower(sess.Project), strings.ToLower(filter.Project)) {
+			continue
+		}
+		u := "/session/" + url.PathEscape(sess.ID)
+		if len(link) > 0 {
+			u += "?" + link.Encode()
+		}
+		entries = append(entries, IndexEntry{
+			Title:   sess.FirstPrompt,
+			Project: filepath.Base(sess.Project),
+			Date:    indexDate(sess.Timestamp, s.loc),
+			URL:     u,
+		})
+	}
+	// ParseHistory returns newest first.
+	if filter.Sort == "oldest" {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+
+	page, err := RenderIndex(IndexPage{Title: "Claude Code Sessions", Entries: entries, Theme: theme, Filter: filter})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeHTML(w, page)
+}
+
+func (s *server) handleSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	q := r.URL.Query()
+	theme, ok := s.pageTheme(w, q)
+	if !ok {
+		return
+	}
+	opts := ParseOpts{
+		IncludeTools:    queryBool(q, "tools"),
+		IncludeThinking: queryBool(q, "thinking"),
+	}
+
+	tail, release, err := s.sessionTail(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer release()
+	if _, err := tail.Poll(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	messages := tail.Messages(opts)
+	meta, _, err := sessionMeta(s.claudeDirs, tail, id, len(messages), s.loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderOpts := RenderOpts{
+		IncludeTools:    opts.IncludeTools,
+		IncludeThinking: opts.IncludeThinking,
+		Theme:           theme,
+		Location:        s.loc,
+	}
+	if s.watch {
+		renderOpts.LiveURL = fmt.Sprintf("/session/%s/events?since=%d", url.PathEscape(id), tail.Len())
+	}
+	page, err := RenderHTML(messages, meta, renderOpts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeHTML(w, page)
+}
+
+// handleEvents streams an "update" event whenever the session gains rows
+// beyond the ?since= count the page was rendered with. Every stream checks
+// the row count on each tick, since another request sharing the tail may
+// have been the one to read the new rows.
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	tail, release, err := s.sessionTail(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer release()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": watching\n\n")
+	flusher.Flush()
+
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	notify := func() error {
+		if n := tail.Len(); n > since {
+			since = n
+			if _, err := fmt.Fprintf(w, "event: update\ndata: %d\n\n", n); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+		return nil
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := tail.Poll(); err != nil || notify() != nil {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sharedTail is a session tail plus the number of requests using it.
+type sharedTail struct {
+	tail  *sessionTail
+	users int
+}
+
+// sessionTail returns the shared tail for a session, so a watched session
+// is only ever read incrementally while it's open somewhere. The caller must
+// call release when done with it. Without watch mode every request reads
+// the file afresh.
+func (s *server) sessionTail(id string) (*sessionTail, func(), error) {
+	if !validSessionID(id) {
+		return nil, nil, fmt.Errorf("session %s not found", id)
+	}
+	if !s.watch {
+		path, err := FindSessionPath(s.claudeDirs, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		return newSessionTail(path), func() {}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.tails[id]
+	if !ok {
+		path, err := FindSessionPath(s.claudeDirs, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		st = &sharedTail{tail: newSessionTail(path)}
+		s.tails[id] = st
+	}
+	st.users++
+	release := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if st.users--; st.users == 0 {
+			delete(s.tails, id)
+		}
+	}
+	return st.tail, release, nil
+}
+
+// pageTheme returns the theme requested with ?theme=, falling back to the
+// server default. It writes a 400 response for unknown themes.
+func (s *server) pageTheme(w http.ResponseWriter, q url.Values) (string, bool) {
+	theme := q.Get("theme")
+	if theme == "" {
+		return s.theme, true
+	}
+	if !validTheme(theme) {
+		http.Error(w, fmt.Sprintf("unknown theme %q", theme), http.StatusBadRequest)
+		return "", false
+	}
+	return theme, true
+}
+
+func queryBool(q url.Values, key string) bool {
+	switch q.Get(key) {
+	case "1", "true", "on", "yes":
+		return true
+	}
+	return false
+}
+
+func writeHTML(w http.ResponseWriter, page string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
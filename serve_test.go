@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeServeFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTempFile(t, dir, "history.jsonl",
+		`{"display":"older webapp prompt","timestamp":1000,"project":"/home/user/webapp","sessionId":"s1"}
+{"display":"newer cli prompt","timestamp":2000,"project":"/home/user/cli","sessionId":"s2"}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-webapp", "s1.jsonl"),
+		`{"type":"user","uuid":"u1","message":{"role":"user","content":"Hello there"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","message":{"id":"m1","role":"assistant","content":[{"type":"thinking","thinking":"pondering deeply"},{"type":"text","text":"Hi!"}]}}
+`)
+	return dir
+}
+
+func get(t *testing.T, h http.Handler, target string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return rec.Code, string(body)
+}
+
+func TestServe_IndexListsSessionsNewestFirst(t *testing.T) {
+	h := newServer([]string{writeServeFixture(t)}, "dark", nil, false)
+
+	code, body := get(t, h, "/")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `href="/session/s1"`)
+	assert.Less(t, strings.Index(body, "newer cli prompt"), strings.Index(body, "older webapp prompt"))
+
+	_, body = get(t, h, "/?sort=oldest")
+	assert.Less(t, strings.Index(body, "older webapp prompt"), strings.Index(body, "newer cli prompt"))
+}
+
+func TestServe_IndexFiltersByProjectAndKeepsOptions(t *testing.T) {
+	h := newServer([]string{writeServeFixture(t)}, "dark", nil, false)
+
+	code, body := get(t, h, "/?project=WEB&tools=1&thinking=on")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "older webapp prompt")
+	assert.NotContains(t, body, "newer cli prompt")
+	assert.Contains(t, body, `href="/session/s1?thinking=1&amp;tools=1"`)
+	assert.NotContains(t, body, `name="theme"`)
+
+	_, body = get(t, h, "/?theme=light")
+	assert.Contains(t, body, `<input type="hidden" name="theme" value="light">`)
+}
+
+func TestServe_SessionRendersWithOptions(t *testing.T) {
+	h := newServer([]string{writeServeFixture(t)}, "dark", nil, false)
+
+	code, body := get(t, h, "/session/s1")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "older webapp prompt")
+	assert.Contains(t, body, "Hi!")
+	assert.NotContains(t, body, "pondering deeply")
+
+	_, body = get(t, h, "/session/s1?thinking=1&theme=light")
+	assert.Contains(t, body, "pondering deeply")
+	assert.Contains(t, body, "--bg:#faf9f5")
+}
+
+func TestServe_Errors(t *testing.T) {
+	h := newServer([]string{writeServeFixture(t)}, "dark", nil, false)
+
+	code, _ := get(t, h, "/session/missing")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = get(t, h, "/session/s1?theme=neon")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = get(t, h, "/nope")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestServe_WatchStreamsUpdates(t *testing.T) {
+	dir := writeServeFixture(t)
+	s := newServer([]string{dir}, "dark", nil, true)
+	s.interval = 10 * time.Millisecond
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	_, body := get(t, s, "/session/s1")
+	assert.Contains(t, body, `/session/s1/events?since=2`)
+
+	resp, err := http.Get(ts.URL + "/session/s1/events?since=2")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	appendFile(t, filepath.Join(dir, "projects", "-home-user-webapp", "s1.jsonl"),
+		`{"type":"user","uuid":"u2","parentUuid":"a1","message":{"role":"user","content":"More"}}
+`)
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line == "event: update\n" {
+			break
+		}
+	}
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: 3\n", line)
+}
+
+func TestServe_WatchNotifiesEveryStream(t *testing.T) {
+	dir := writeServeFixture(t)
+	s := newServer([]string{dir}, "dark", nil, true)
+	s.interval = 10 * time.Millisecond
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var streams []*http.Response
+	for range 2 {
+		resp, err := http.Get(ts.URL + "/session/s1/events?since=2")
+		require.NoError(t, err)
+		streams = append(streams, resp)
+	}
+	appendFile(t, filepath.Join(dir, "projects", "-home-user-webapp", "s1.jsonl"),
+		`{"type":"user","uuid":"u2","parentUuid":"a1","message":{"role":"user","content":"More"}}
+`)
+	// A page reload reading the new rows first must not hide them from
+	// the open streams.
+	_, body := get(t, s, "/session/s1")
+	assert.Contains(t, body, `/session/s1/events?since=3`)
+
+	for _, resp := range streams {
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "event: update\n" {
+				break
+			}
+		}
+		resp.Body.Close()
+	}
+
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.tails) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServe_EventsOnlyInWatchMode(t *testing.T) {
+	h := newServer([]string{writeServeFixture(t)}, "dark", nil, false)
+
+	code, _ := get(t, h, "/session/s1/events")
+	assert.Equal(t, http.StatusNotFound, code)
+	_, body := get(t, h, "/session/s1")
+	assert.NotContains(t, body, "EventSource")
+}
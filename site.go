@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type SiteOpts struct {
+	Project         string // project path substring; empty means all sessions
+	IncludeTools    bool
+	IncludeThinking bool
+	Theme           string
+	Location        *time.Location // time zone of message times; nil means local
+	Images          ImageOpts
+}
+
+// SiteResult reports what BuildSite wrote and which sessions it had to skip.
+type SiteResult struct {
+	Pages   int
+	Skipped []string // "<id>: <reason>"
+}
+
+// BuildSite writes one page per matching session plus an index.html into
+// outDir. Sessions are listed newest first; each page links to the previous
+// (older) and next (newer) session.
+func BuildSite(claudeDirs []string, outDir string, opts SiteOpts) (SiteResult, error) {
+	var result SiteResult
+
+	sessions, err := ListSessions(claudeDirs)
+	if err != nil {
+		return result, err
+	}
+	parseOpts := ParseOpts{IncludeTools: opts.IncludeTools, IncludeThinking: opts.IncludeThinking}
+
+	type sitePage struct {
+		summary SessionSummary
+		path    string
+		count   int
+	}
+	var pages []sitePage
+	for _, s := range sessions {
+		if opts.Project != "" && !strings.Contains(strings.ToLower(s.Project), strings.ToLower(opts.Project)) {
+			continue
+		}
+		// Each page is written as <id>.html next to index.html.
+		if !validSessionID(s.ID) || s.ID == "index" {
+			result.Skipped = append(result.Skipped, s.ID+": invalid session ID")
+			continue
+		}
+		path, err := FindSessionPath(claudeDirs, s.ID)
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", s.ID, err))
+			continue
+		}
+		messages, err := ParseSession(path, parseOpts)
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", s.ID, err))
+			continue
+		}
+		if len(messages) == 0 {
+			result.Skipped = append(result.Skipped, s.ID+": no messages")
+			continue
+		}
+		pages = append(pages, sitePage{summary: s, path: path, count: len(messages)})
+	}
+	if len(pages) == 0 {
+		return result, fmt.Errorf("no sessions to publish")
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return result, fmt.Errorf("create output dir: %w", err)
+	}
+
+	title := "Claude Code Sessions"
+	projects := make(map[string]bool)
+	entries := make([]IndexEntry, len(pages))
+	for i, p := range pages {
+		projects[p.summary.Project] = true
+		entries[i] = IndexEntry{
+			Title:        p.summary.FirstPrompt,
+			Project:      filepath.Base(p.summary.Project),
+			Date:         indexDate(p.summary.Timestamp, opts.Location),
+			MessageCount: p.count,
+			URL:          url.PathEscape(sitePageName(p.summary.ID)),
+		}
+	}
+	if len(projects) == 1 {
+		title = entries[0].Project + " — " + title
+	}
+
+	for i, p := range pages {
+		// Sessions were parsed once already to count messages; parse again
+		// rather than keeping every conversation in memory.
+		tail := newSessionTail(p.path)
+		if _, err := tail.Poll(); err != nil {
+			return result, err
+		}
+		messages := tail.Messages(parseOpts)
+		meta := summaryMeta(p.summary, len(messages), opts.Location)
+		meta.SessionDetails = tail.Details()
+		nav := &PageNav{Index: "index.html"}
+		if i+1 < len(pages) {
+			nav.Prev, nav.PrevTitle = entries[i+1].URL, siteNavTitle(entries[i+1].Title)
+		}
+		if i > 0 {
+			nav.Next, nav.NextTitle = entries[i-1].URL, siteNavTitle(entries[i-1].Title)
+		}
+		processImages(messages, opts.Images)
+		page, err := RenderHTML(messages, meta, RenderOpts{
+			IncludeTools:    opts.IncludeTools,
+			IncludeThinking: opts.IncludeThinking,
+			Theme:           opts.Theme,
+			Location:        opts.Location,
+			Nav:             nav,
+		})
+		if err != nil {
+			return result, fmt.Errorf("render %s: %w", p.summary.ID, err)
+		}
+		if err := os.WriteFile(filepath.Join(outDir, sitePageName(p.summary.ID)), []byte(page), 0644); err != nil {
+			return result, fmt.Errorf("write page: %w", err)
+		}
+		result.Pages++
+	}
+
+	index, err := RenderIndex(IndexPage{Title: title, Entries: entries, Theme: opts.Theme})
+	if err != nil {
+		return result, err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "index.html"), []byte(index), 0644); err != nil {
+		return result, fmt.Errorf("write index: %w", err)
+	}
+	return result, nil
+}
+
+func sitePageName(sessionID string) string {
+	return sessionID + ".html"
+}
+
+func siteNavTitle(title string) string {
+	if title == "" {
+		return "Claude Conversation"
+	}
+	return title
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSiteFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTempFile(t, dir, "history.jsonl",
+		`{"display":"first task","timestamp":1000,"project":"/home/user/webapp","sessionId":"s1"}
+{"display":"second task","timestamp":2000,"project":"/home/user/webapp","sessionId":"s2"}
+{"display":"third task","timestamp":3000,"project":"/home/user/webapp","sessionId":"s3"}
+{"display":"other project","timestamp":4000,"project":"/home/user/cli","sessionId":"s4"}
+{"display":"lost session","timestamp":5000,"project":"/home/user/webapp","sessionId":"gone"}
+`)
+	for _, id := range []string{"s1", "s2", "s3", "s4"} {
+		writeTempFile(t, dir, filepath.Join("projects", "-home-user-x", id+".jsonl"),
+			`{"type":"user","uuid":"u1","message":{"role":"user","content":"hello from `+id+`"}}
+`)
+	}
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestBuildSite_PagesIndexAndNavigation(t *testing.T) {
+	dir := writeSiteFixture(t)
+	out := filepath.Join(t.TempDir(), "site")
+
+	result, err := BuildSite([]string{dir}, out, SiteOpts{Project: "webapp"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Pages)
+	require.Len(t, result.Skipped, 1)
+	assert.Contains(t, result.Skipped[0], "gone")
+
+	index := readFile(t, filepath.Join(out, "index.html"))
+	assert.Contains(t, index, "webapp — Claude Code Sessions")
+	assert.Contains(t, index, `href="s3.html"`)
+	assert.Contains(t, index, "1 message<")
+	assert.NotContains(t, index, "other project")
+	assert.NotContains(t, index, "<form")
+	assert.NoFileExists(t, filepath.Join(out, "s4.html"))
+
+	middle := readFile(t, filepath.Join(out, "s2.html"))
+	assert.Contains(t, middle, "hello from s2")
+	assert.Contains(t, middle, `href="index.html"`)
+	assert.Contains(t, middle, `href="s1.html"`)
+	assert.Contains(t, middle, `href="s3.html"`)
+
+	newest := readFile(t, filepath.Join(out, "s3.html"))
+	assert.Contains(t, newest, `href="s2.html"`)
+	assert.NotContains(t, newest, "Next →")
+}
+
+func TestBuildSite_SkipsUnsafeSessionIDs(t *testing.T) {
+	dir := t.TempDir()
+	writeTempFile(t, dir, "history.jsonl",
+		`{"display":"escape","timestamp":1000,"project":"/home/user/webapp","sessionId":"../escape"}
+{"display":"clobber","timestamp":2000,"project":"/home/user/webapp","sessionId":"index"}
+{"display":"fine","timestamp":3000,"project":"/home/user/webapp","sessionId":"s1"}
+`)
+	writeTempFile(t, dir, filepath.Join("projects", "-home-user-x", "s1.jsonl"),
+		`{"type":"user","uuid":"u1","message":{"role":"user","content":"hello"}}
+`)
+	out := filepath.Join(t.TempDir(), "site")
+
+	result, err := BuildSite([]string{dir}, out, SiteOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pages)
+	assert.Equal(t, []string{"index: invalid session ID", "../escape: invalid session ID"}, result.Skipped)
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(out), "escape.html"))
+	assert.Contains(t, readFile(t, filepath.Join(out, "index.html")), "Claude Code Sessions")
+}
+
+func TestBuildSite_NoMatchingSessions(t *testing.T) {
+	dir := writeSiteFixture(t)
+
+	_, err := BuildSite([]string{dir}, t.TempDir(), SiteOpts{Project: "nothing-matches"})
+	assert.Error(t, err)
+}
+
+func TestBuildSite_PagesHaveNoExternalAssets(t *testing.T) {
+	dir := writeSiteFixture(t)
+	out := t.TempDir()
+
+	_, err := BuildSite([]string{dir}, out, SiteOpts{Project: "cli", Theme: "auto"})
+	require.NoError(t, err)
+	for _, name := range []string{"index.html", "s4.html"} {
+		page := readFile(t, filepath.Join(out, name))
+		assert.NotRegexp(t, `(src|href)="https?://[^"]*\.(css|js)"`, page)
+		assert.NotContains(t, page, "<script src=")
+		assert.NotContains(t, page, `rel="stylesheet"`)
+	}
+}
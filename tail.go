@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionTail keeps the rows of a session file and its subagent files in
+// memory so a growing session can be re-read cheaply.
+type sessionTail struct {
+	mu        sync.Mutex
+	path      string
+	main      rowFile
+	subagents map[string]*rowFile
+}
+
+func newSessionTail(path string) *sessionTail {
+	return &sessionTail{path: path, subagents: make(map[string]*rowFile)}
+}
+
+// readSessionTail reads a whole session from r, e.g. stdin. The result has
+// no file behind it, so Poll never finds anything new.
+func readSessionTail(r io.Reader) (*sessionTail, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read session: %w", err)
+	}
+	t := newSessionTail("")
+	t.main.parse(data)
+	return t, nil
+}
+
+// Poll reads whatever was appended to the session since the last call and
+// reports whether any rows were added.
+func (t *sessionTail) Poll() (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.path == "" {
+		return false, nil
+	}
+
+	changed, err := t.main.read(t.path)
+	if err != nil {
+		return false, err
+	}
+
+	// Older Claude Code versions write subagent rows into the session file
+	// itself; newer ones keep them in <session>/subagents/*.jsonl.
+	files, _ := filepath.Glob(filepath.Join(strings.TrimSuffix(t.path, ".jsonl"), "subagents", "*.jsonl"))
+	for _, f := range files {
+		rf, ok := t.subagents[f]
+		if !ok {
+			rf = &rowFile{}
+			t.subagents[f] = rf
+		}
+		added, err := rf.read(f)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || added
+	}
+	return changed, nil
+}
+
+// Len returns the number of rows read so far.
+func (t *sessionTail) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := len(t.main.rows)
+	for _, rf := range t.subagents {
+		n += len(rf.rows)
+	}
+	return n
+}
+
+// Summary describes the session the way a history entry would, using the
+// rows read so far. ID is the sessionId recorded in the rows, if any.
+func (t *sessionTail) Summary() SessionSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sum sessionSummarizer
+	for _, row := range t.main.rows {
+		if sum.add(row) {
+			break
+		}
+	}
+	return sum.s
+}
+
+// Details collects the working directory, git branch, Claude Code version
+// and models of the rows read so far. The branch and version are the latest
+// ones recorded, since both can change while a session runs.
+func (t *sessionTail) Details() SessionDetails {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var d SessionDetails
+	for _, row := range t.main.rows {
+		if d.Cwd == "" {
+			d.Cwd = row.Cwd
+		}
+		if row.GitBranch != "" {
+			d.GitBranch = row.GitBranch
+		}
+		if row.Version != "" {
+			d.Version = row.Version
+		}
+	}
+	seen := make(map[string]bool)
+	for _, row := range t.rows() {
+		if row.Type != "assistant" || row.Message == nil {
+			continue
+		}
+		var api apiMessage
+		if err := json.Unmarshal(row.Message, &api); err != nil {
+			continue
+		}
+		if api.Model != "" && api.Model != syntheticModel && !seen[api.Model] {
+			seen[api.Model] = true
+			d.Models = append(d.Models, api.Model)
+		}
+	}
+	return d
+}
+
+// Messages builds the conversation from every row read so far.
+func (t *sessionTail) Messages(opts ParseOpts) []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return buildSession(t.rows(), opts)
+}
+
+// Usage totals the tokens of every assistant message read so far, including
+// subagents and abandoned branches, since those were paid for too.
+func (t *sessionTail) Usage(prices PriceTable) UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var c usageCollector
+	c.addRows(t.rows())
+	return c.report(prices)
+}
+
+// Tools counts the tool calls read so far, and how many of them failed, per
+// tool name.
+func (t *sessionTail) Tools() map[string]ToolStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return collectToolStats(t.rows())
+}
+
+// rows returns the main rows followed by the subagent rows, marked as
+// sidechain rows. The caller must hold t.mu.
+func (t *sessionTail) rows() []sessionRow {
+	rows := slices.Clone(t.main.rows)
+	for _, f := range slices.Sorted(maps.Keys(t.subagents)) {
+		for _, row := range t.subagents[f].rows {
+			row.IsSidechain = true
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// watchInterval is how often watch modes check a session for new rows.
+const watchInterval = 500 * time.Millisecond
+
+// watchSession polls t every interval and calls onChange whenever new rows
+// were read, until ctx is cancelled or either returns an error.
+func watchSession(ctx context.Context, t *sessionTail, interval time.Duration, onChange func() error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		changed, err := t.Poll()
+		if err != nil {
+			return err
+		}
+		if changed {
+			if err := onChange(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// rowFile holds the rows read so far from a JSONL file that may still be
+// growing. Each read only parses what was appended since the last one.
+type rowFile struct {
+	offset  int64
+	partial []byte
+	rows    []sessionRow
+}
+
+// read parses rows appended to path since the previous call and reports
+// whether any were added. If the file shrank it is read again from the
+// start.
+func (rf *rowFile) read(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("open session: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, fmt.Errorf("stat session: %w", err)
+	}
+	if info.Size() < rf.offset {
+		*rf = rowFile{}
+	}
+	if _, err := f.Seek(rf.offset, io.SeekStart); err != nil {
+		return false, fmt.Errorf("seek session: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return false, fmt.Errorf("read session: %w", err)
+	}
+	rf.offset += int64(len(data))
+	return rf.parse(data), nil
+}
+
+// parse adds the rows in data, which continues where the previous data
+// ended, and reports whether any were added.
+func (rf *rowFile) parse(data []byte) bool {
+	data = append(rf.partial, data...)
+	rf.partial = nil
+
+	before := len(rf.rows)
+	for len(data) > 0 {
+		line, rest, complete := bytes.Cut(data, []byte("\n"))
+		var row sessionRow
+		if err := json.Unmarshal(line, &row); err != nil {
+			// An unterminated last line is usually still being written.
+			if !complete {
+				rf.partial = bytes.Clone(line)
+			}
+		} else {
+			rf.rows = append(rf.rows, row)
+		}
+		data = rest
+	}
+	return len(rf.rows) > before
+}
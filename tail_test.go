@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestRowFile_ReadsOnlyAppendedRows(t *testing.T) {
+	path := writeSession(t, `{"type":"user","uuid":"u1"}
+{"type":"assistant","uu`)
+
+	var rf rowFile
+	added, err := rf.read(path)
+	require.NoError(t, err)
+	assert.True(t, added)
+	require.Len(t, rf.rows, 1)
+
+	added, err = rf.read(path)
+	require.NoError(t, err)
+	assert.False(t, added)
+
+	appendFile(t, path, `id":"a1"}
+{"type":"user","uuid":"u2"}
+`)
+	added, err = rf.read(path)
+	require.NoError(t, err)
+	assert.True(t, added)
+	require.Len(t, rf.rows, 3)
+	assert.Equal(t, "a1", rf.rows[1].UUID)
+	assert.Equal(t, "u2", rf.rows[2].UUID)
+}
+
+func TestRowFile_UnterminatedCompleteRow(t *testing.T) {
+	path := writeSession(t, `{"type":"user","uuid":"u1"}`)
+
+	var rf rowFile
+	_, err := rf.read(path)
+	require.NoError(t, err)
+	assert.Len(t, rf.rows, 1)
+	assert.Empty(t, rf.partial)
+}
+
+func TestRowFile_RereadsTruncatedFile(t *testing.T) {
+	path := writeSession(t, `{"type":"user","uuid":"u1"}
+{"type":"user","uuid":"u2"}
+`)
+	var rf rowFile
+	_, err := rf.read(path)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type":"user","uuid":"u3"}
+`), 0644))
+	added, err := rf.read(path)
+	require.NoError(t, err)
+	assert.True(t, added)
+	require.Len(t, rf.rows, 1)
+	assert.Equal(t, "u3", rf.rows[0].UUID)
+}
+
+func TestSessionTail_PicksUpNewSubagentFiles(t *testing.T) {
+	path := writeSession(t, `{"type":"user","uuid":"u1","message":{"role":"user","content":"go"}}
+`)
+	tail := newSessionTail(path)
+	_, err := tail.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, 1, tail.Len())
+
+	writeTempFile(t, filepath.Dir(path), filepath.Join("session", "subagents", "agent-1.jsonl"),
+		`{"type":"user","uuid":"s1","message":{"role":"user","content":"sub"}}
+`)
+	changed, err := tail.Poll()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 2, tail.Len())
+	assert.Len(t, tail.Messages(ParseOpts{}), 1)
+}
+
+func TestWatchSession_CallsOnChange(t *testing.T) {
+	path := writeSession(t, `{"type":"user","uuid":"u1","message":{"role":"user","content":"first"}}
+`)
+	tail := newSessionTail(path)
+	_, err := tail.Poll()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	appendFile(t, path, `{"type":"user","uuid":"u2","parentUuid":"u1","message":{"role":"user","content":"second"}}
+`)
+	var got []Message
+	err = watchSession(ctx, tail, 10*time.Millisecond, func() error {
+		got = tail.Messages(ParseOpts{})
+		cancel()
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "second", got[1].Blocks[0].Text)
+}
+
+func TestReadSessionTail_FromReader(t *testing.T) {
+	tail, err := readSessionTail(strings.NewReader(`{"type":"summary","summary":"x"}
+{"type":"user","uuid":"u1","sessionId":"sess-1","cwd":"/home/bob/app","timestamp":"2025-02-01T10:00:00Z","message":{"role":"user","content":"from a colleague"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","message":{"id":"m1","role":"assistant","content":[{"type":"text","text":"Hi"}]}}`))
+	require.NoError(t, err)
+
+	changed, err := tail.Poll()
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Len(t, tail.Messages(ParseOpts{}), 2)
+
+	sum := tail.Summary()
+	assert.Equal(t, "sess-1", sum.ID)
+	assert.Equal(t, "/home/bob/app", sum.Project)
+	assert.Equal(t, "from a colleague", sum.FirstPrompt)
+	assert.Equal(t, time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC).UnixMilli(), sum.Timestamp)
+}
+
+func TestSessionTail_Details(t *testing.T) {
+	tail, err := readSessionTail(strings.NewReader(`{"type":"user","uuid":"u1","cwd":"/home/bob/app","gitBranch":"main","version":"1.0.40","message":{"role":"user","content":"hi"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","message":{"id":"m1","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"text","text":"Hi"}]}}
+{"type":"assistant","uuid":"a2","parentUuid":"a1","message":{"id":"m2","model":"<synthetic>","role":"assistant","content":[{"type":"text","text":"API Error"}]}}
+{"type":"user","uuid":"u2","parentUuid":"a2","cwd":"/home/bob/app/web","gitBranch":"fix-login","version":"1.0.43","message":{"role":"user","content":"again"}}
+{"type":"assistant","uuid":"a3","parentUuid":"u2","message":{"id":"m3","model":"claude-opus-4-5","role":"assistant","content":[{"type":"text","text":"Sure"}]}}
+{"type":"assistant","uuid":"a4","parentUuid":"a3","message":{"id":"m4","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"text","text":"Done"}]}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, SessionDetails{
+		Cwd:       "/home/bob/app",
+		GitBranch: "fix-login",
+		Version:   "1.0.43",
+		Models:    []string{"claude-sonnet-4-5", "claude-opus-4-5"},
+	}, tail.Details())
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Usage counts the tokens of one or more assistant messages.
+type Usage struct {
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	CacheReadTokens     int64 `json:"cache_read_tokens"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens"`
+}
+
+func (u apiUsage) usage() Usage {
+	return Usage{
+		InputTokens:         u.InputTokens,
+		OutputTokens:        u.OutputTokens,
+		CacheReadTokens:     u.CacheReadInputTokens,
+		CacheCreationTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// Tokens is the total of all token kinds.
+func (u Usage) Tokens() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheCreationTokens
+}
+
+func (u *Usage) Add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CacheCreationTokens += o.CacheCreationTokens
+}
+
+// ModelPrice is what a model costs in US dollars per million tokens.
+type ModelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read"`
+	CacheWrite float64 `json:"cache_write"`
+}
+
+func (p ModelPrice) Cost(u Usage) float64 {
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheReadTokens)*p.CacheRead +
+		float64(u.CacheCreationTokens)*p.CacheWrite) / 1e6
+}
+
+// PriceTable maps model names to prices. A dated snapshot such as
+// "claude-sonnet-4-5-20250929" is priced under its undated name.
+type PriceTable map[string]ModelPrice
+
+// defaultPrices are Anthropic's list prices, with 5-minute cache writes.
+// Models are listed one by one: a newer model is left unpriced rather than
+// charged at an older model's rate.
+var defaultPrices = PriceTable{
+	"claude-opus-4-5":          {Input: 5, Output: 25, CacheRead: 0.5, CacheWrite: 6.25},
+	"claude-opus-4-1":          {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-opus-4":            {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-opus-4-0":          {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-sonnet-4-5":        {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-sonnet-4":          {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-sonnet-4-0":        {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-7-sonnet":        {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-7-sonnet-latest": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-5-sonnet":        {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-5-sonnet-latest": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-haiku-4-5":         {Input: 1, Output: 5, CacheRead: 0.1, CacheWrite: 1.25},
+	"claude-3-5-haiku":         {Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	"claude-3-5-haiku-latest":  {Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+}
+
+// LoadPriceTable reads a JSON object of model name to price and lays it over
+// the default prices.
+func LoadPriceTable(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read price table: %w", err)
+	}
+	var custom PriceTable
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parse price table %s: %w", path, err)
+	}
+	prices := make(PriceTable, len(defaultPrices)+len(custom))
+	for k, v := range defaultPrices {
+		prices[k] = v
+	}
+	for k, v := range custom {
+		prices[k] = v
+	}
+	return prices, nil
+}
+
+func (t PriceTable) Lookup(model string) (ModelPrice, bool) {
+	if p, ok := t[model]; ok {
+		return p, true
+	}
+	p, ok := t[trimModelDate(model)]
+	return p, ok
+}
+
+// trimModelDate strips a snapshot date like "-20250929" from a model name.
+func trimModelDate(model string) string {
+	i := strings.LastIndexByte(model, '-')
+	if i < 0 || len(model)-i-1 != 8 {
+		return model
+	}
+	for _, c := range model[i+1:] {
+		if c < '0' || c > '9' {
+			return model
+		}
+	}
+	return model[:i]
+}
+
+// ModelUsage is the usage and estimated cost of one model, or of all of them.
+type ModelUsage struct {
+	Model    string  `json:"model,omitempty"`
+	Messages int     `json:"messages"`
+	Usage    Usage   `json:"usage"`
+	Cost     float64 `json:"cost_usd"`
+	Priced   bool    `json:"priced"` // false if Cost leaves out a model with no known price
+}
+
+// UsageReport sums token usage per model. Total.Priced is false if any model
+// had no price.
+type UsageReport struct {
+	Models []ModelUsage `json:"models"`
+	Total  ModelUsage   `json:"total"`
+}
+
+// Claude Code records API errors and interruptions as assistant messages
+// from this model. They use no tokens.
+const syntheticModel = "<synthetic>"
+
+// usageCollector totals assistant message usage per model.
+type usageCollector struct {
+	models map[string]*ModelUsage
+}
+
+func (c *usageCollector) add(model string, u Usage) {
+	if c.models == nil {
+		c.models = make(map[string]*ModelUsage)
+	}
+	m, ok := c.models[model]
+	if !ok {
+		m = &ModelUsage{Model: model}
+		c.models[model] = m
+	}
+	m.Messages++
+	m.Usage.Add(u)
+}
+
+// addRows adds the assistant messages of a session's rows, counting streamed
+// rows of the same message once.
+func (c *usageCollector) addRows(rows []sessionRow) {
+	type message struct {
+		model string
+		usage Usage
+	}
+	byID := make(map[string]message)
+	var order []string
+	for _, row := range rows {
+		if row.Type != "assistant" || row.Message == nil {
+			continue
+		}
+		var api apiMessage
+		if err := json.Unmarshal(row.Message, &api); err != nil || api.Usage == nil {
+			continue
+		}
+		if api.Model == syntheticModel {
+			continue
+		}
+		if api.ID == "" {
+			c.add(api.Model, api.Usage.usage())
+			continue
+		}
+		if _, ok := byID[api.ID]; !ok {
+			order = append(order, api.ID)
+		}
+		byID[api.ID] = message{model: api.Model, usage: api.Usage.usage()}
+	}
+	for _, id := range order {
+		c.add(byID[id].model, byID[id].usage)
+	}
+}
+
+func (c *usageCollector) report(prices PriceTable) UsageReport {
+	r := UsageReport{Total: ModelUsage{Priced: true}}
+	for _, m := range c.models {
+		if price, ok := prices.Lookup(m.Model); ok {
+			m.Cost = price.Cost(m.Usage)
+			m.Priced = true
+		}
+		r.Models = append(r.Models, *m)
+		r.Total.Messages += m.Messages
+		r.Total.Usage.Add(m.Usage)
+		r.Total.Cost += m.Cost
+		r.Total.Priced = r.Total.Priced && m.Priced
+	}
+	sort.Slice(r.Models, func(i, j int) bool { return r.Models[i].Model < r.Models[j].Model })
+	return r
+}
+
+// formatTokens abbreviates a token count for display, e.g. 1.2M or 45.6K.
+func formatTokens(n int64) string {
+	switch {
+	case n >= 999_950: // would round to 1000.0K
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1fK", float64(n)/1e3)
+	}
+	return fmt.Sprint(n)
+}
+
+// formatCost shows an estimated cost in dollars. A cost that leaves out
+// unpriced usage is marked with "+", or shown as "?" if nothing was priced.
+func formatCost(cost float64, priced bool) string {
+	if !priced && cost == 0 {
+		return "?"
+	}
+	s := fmt.Sprintf("$%.2f", cost)
+	if !priced {
+		s += "+"
+	}
+	return s
+}
+
+// writeUsageTable prints one row per model, plus a total if there are
+// several.
+func writeUsageTable(w io.Writer, r UsageReport) {
+	fmt.Fprintf(w, "%-30s  %8s  %12s  %12s  %12s  %12s  %10s\n", "Model", "Messages", "Input", "Output", "Cache read", "Cache write", "Cost")
+	row := func(name string, m ModelUsage) {
+		fmt.Fprintf(w, "%-30s  %8d  %12s  %12s  %12s  %12s  %10s\n", name, m.Messages,
+			groupDigits(m.Usage.InputTokens), groupDigits(m.Usage.OutputTokens),
+			groupDigits(m.Usage.CacheReadTokens), groupDigits(m.Usage.CacheCreationTokens), formatCost(m.Cost, m.Priced))
+	}
+	for _, m := range r.Models {
+		name := m.Model
+		if name == "" {
+			name = "(unknown)"
+		}
+		row(name, m)
+	}
+	if len(r.Models) != 1 {
+		row("Total", r.Total)
+	}
+}
+
+// groupDigits formats n with thousands separators.
+func groupDigits(n int64) string {
+	if n < 0 {
+		return "-" + groupDigits(-n)
+	}
+	s := strconv.FormatInt(n, 10)
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceTable_Lookup(t *testing.T) {
+	p, ok := defaultPrices.Lookup("claude-opus-4-5-20251101")
+	require.True(t, ok)
+	assert.Equal(t, 5.0, p.Input)
+
+	p, ok = defaultPrices.Lookup("claude-opus-4-1-20250805")
+	require.True(t, ok)
+	assert.Equal(t, 15.0, p.Input)
+
+	p, ok = defaultPrices.Lookup("claude-sonnet-4-5")
+	require.True(t, ok)
+	assert.Equal(t, 3.0, p.Input)
+
+	_, ok = defaultPrices.Lookup("claude-opus-4-7")
+	assert.False(t, ok, "unlisted models must not be priced like an older one")
+
+	_, ok = defaultPrices.Lookup("gpt-4")
+	assert.False(t, ok)
+}
+
+func TestModelPrice_Cost(t *testing.T) {
+	p := ModelPrice{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75}
+	u := Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheReadTokens: 2_000_000, CacheCreationTokens: 400_000}
+	assert.InDelta(t, 3+1.5+0.6+1.5, p.Cost(u), 1e-9)
+}
+
+func TestLoadPriceTable_OverridesDefaults(t *testing.T) {
+	path := writeTempFile(t, t.TempDir(), "prices.json",
+		`{"claude-sonnet-4": {"input": 1, "output": 2}, "my-model": {"input": 4}}`)
+
+	prices, err := LoadPriceTable(path)
+	require.NoError(t, err)
+	assert.Equal(t, ModelPrice{Input: 1, Output: 2}, prices["claude-sonnet-4"])
+	assert.Equal(t, 4.0, prices["my-model"].Input)
+	assert.Equal(t, defaultPrices["claude-opus-4"], prices["claude-opus-4"])
+	assert.NotEqual(t, defaultPrices["claude-sonnet-4"], prices["claude-sonnet-4"], "defaults must not be modified")
+}
+
+func TestSessionTailUsage(t *testing.T) {
+	path := writeSession(t,
+		`{"type":"user","message":{"role":"user","content":"hi"}}
+{"type":"assistant","message":{"id":"m1","model":"claude-sonnet-4-5","content":[{"type":"thinking","thinking":"..."}],"usage":{"input_tokens":100,"output_tokens":5}}}
+{"type":"assistant","message":{"id":"m1","model":"claude-sonnet-4-5","content":[{"type":"text","text":"hello"}],"usage":{"input_tokens":100,"output_tokens":50}}}
+{"type":"assistant","isSidechain":true,"message":{"id":"m2","model":"claude-haiku-4-5","content":[{"type":"text","text":"sub"}],"usage":{"input_tokens":10,"output_tokens":20,"cache_read_input_tokens":1000}}}
+{"type":"assistant","message":{"id":"m3","model":"<synthetic>","content":[{"type":"text","text":"API Error"}],"usage":{"input_tokens":0,"output_tokens":0}}}
+{"type":"assistant","message":{"id":"m4","model":"local-model","content":[{"type":"text","text":"x"}],"usage":{"input_tokens":1,"output_tokens":1}}}
+`)
+	tail := newSessionTail(path)
+	_, err := tail.Poll()
+	require.NoError(t, err)
+
+	r := tail.Usage(defaultPrices)
+	require.Len(t, r.Models, 3)
+	assert.Equal(t, ModelUsage{Model: "claude-haiku-4-5", Messages: 1, Usage: Usage{InputTokens: 10, OutputTokens: 20, CacheReadTokens: 1000}, Cost: 0.00021, Priced: true}, roundCost(r.Models[0]))
+	assert.Equal(t, "claude-sonnet-4-5", r.Models[1].Model)
+	assert.Equal(t, Usage{InputTokens: 100, OutputTokens: 50}, r.Models[1].Usage)
+	assert.False(t, r.Models[2].Priced)
+
+	assert.Equal(t, 3, r.Total.Messages)
+	assert.Equal(t, int64(71), r.Total.Usage.OutputTokens)
+	assert.False(t, r.Total.Priced)
+	assert.Equal(t, "$0.00+", formatCost(r.Total.Cost, r.Total.Priced))
+	assert.Equal(t, "?", formatCost(r.Models[2].Cost, r.Models[2].Priced))
+}
+
+func roundCost(m ModelUsage) ModelUsage {
+	m.Cost = float64(int64(m.Cost*1e8+0.5)) / 1e8
+	return m
+}
+
+func TestWriteUsageTable(t *testing.T) {
+	var buf bytes.Buffer
+	writeUsageTable(&buf, UsageReport{
+		Models: []ModelUsage{{Model: "claude-sonnet-4-5", Messages: 3, Usage: Usage{InputTokens: 1234567}, Cost: 3.7, Priced: true}},
+		Total:  ModelUsage{Messages: 3, Usage: Usage{InputTokens: 1234567}, Cost: 3.7, Priced: true},
+	})
+	assert.Contains(t, buf.String(), "1,234,567")
+	assert.Contains(t, buf.String(), "$3.70")
+	assert.NotContains(t, buf.String(), "Total", "a single model needs no total row")
+}
+
+func TestFormatTokens(t *testing.T) {
+	assert.Equal(t, "999", formatTokens(999))
+	assert.Equal(t, "45.6K", formatTokens(45_600))
+	assert.Equal(t, "1.2M", formatTokens(1_234_567))
+	assert.Equal(t, "999.9K", formatTokens(999_949))
+	assert.Equal(t, "1.0M", formatTokens(999_950))
+}